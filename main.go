@@ -2,9 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"mcp-rethinkdb-server/server"
 
@@ -12,17 +20,33 @@ import (
 	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
 )
 
+// shutdownTimeout bounds how long the HTTP transport waits for in-flight
+// requests to finish after a termination signal.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
-	// Get RethinkDB connection settings from environment or defaults
-	host := os.Getenv("RETHINKDB_HOST")
-	if host == "" {
-		host = "localhost"
-	}
-	port := os.Getenv("RETHINKDB_PORT")
-	if port == "" {
-		port = "28015"
+	transport := flag.String("transport", envOrDefault("MCP_TRANSPORT", "stdio"), "Transport to serve MCP over: stdio or http")
+	listen := flag.String("listen", envOrDefault("MCP_LISTEN", ":8080"), "Address to listen on when --transport=http")
+	readOnly := flag.Bool("read-only", envBool("MCP_READ_ONLY"), "Disable write_data and reject any query containing a write term")
+	admin := flag.Bool("admin", envBool("MCP_ADMIN"), "Enable the database and table administration tools")
+	policyFile := flag.String("policy-file", os.Getenv("MCP_POLICY_FILE"), "Path to a JSON database/table access policy")
+	schemaFile := flag.String("schema-file", os.Getenv("MCP_SCHEMA_FILE"), "Path to a JSON file mapping database.table to the JSON Schema write_data validates against")
+	schemaTable := flag.String("schema-table", os.Getenv("MCP_SCHEMA_TABLE"), "RethinkDB table (database.table) holding JSON Schemas for write_data, keyed by database.table")
+	auditFile := flag.String("audit-file", os.Getenv("MCP_AUDIT_FILE"), "Path to a JSON lines file recording every write_data call")
+	auditTable := flag.String("audit-table", os.Getenv("MCP_AUDIT_TABLE"), "RethinkDB table (database.table) recording every write_data call")
+	maxAffected := flag.String("max-affected", envOrDefault("MCP_MAX_AFFECTED", "1000"), "Maximum documents a write_data delete or update by keys or filter may touch without confirm_count (0 disables)")
+	maxWatches := flag.String("max-watches", envOrDefault("MCP_MAX_WATCHES", "10"), "Maximum changefeeds a session may have open with watch_table (0 disables)")
+	queryTimeout := flag.String("query-timeout", os.Getenv("MCP_QUERY_TIMEOUT"), "Default query timeout for every tool, e.g. 30s (empty or 0 disables)")
+	flag.Parse()
+
+	if *transport != "stdio" && *transport != "http" {
+		log.Fatalf("Invalid transport %q: must be one of stdio, http", *transport)
 	}
 
+	// Get RethinkDB connection settings from environment or defaults
+	host := envOrDefault("RETHINKDB_HOST", "localhost")
+	port := envOrDefault("RETHINKDB_PORT", "28015")
+
 	address := fmt.Sprintf("%s:%s", host, port)
 
 	// Connect to RethinkDB
@@ -47,20 +71,225 @@ func main() {
 	// Log connection success to stderr (stdout is for MCP communication)
 	fmt.Fprintf(os.Stderr, "Connected to RethinkDB at %s\n", address)
 
+	policy, err := loadPolicy(*policyFile)
+	if err != nil {
+		log.Fatalf("Failed to load access policy: %v", err)
+	}
+
+	var schemas *server.TableSchemas
+	if *schemaFile != "" {
+		if schemas, err = server.LoadTableSchemas(*schemaFile); err != nil {
+			log.Fatalf("Failed to load schema file: %v", err)
+		}
+	}
+	if db, table, ok := strings.Cut(*schemaTable, "."); *schemaTable != "" && (!ok || db == "" || table == "") {
+		log.Fatalf("Invalid schema table %q: must have the form database.table", *schemaTable)
+	}
+
+	if *auditFile != "" && *auditTable != "" {
+		log.Fatalf("Set only one of --audit-file and --audit-table")
+	}
+	if db, table, ok := strings.Cut(*auditTable, "."); *auditTable != "" && (!ok || db == "" || table == "") {
+		log.Fatalf("Invalid audit table %q: must have the form database.table", *auditTable)
+	}
+	var audit *server.AuditFile
+	if *auditFile != "" {
+		if audit, err = server.OpenAuditFile(*auditFile); err != nil {
+			log.Fatalf("Failed to open audit file: %v", err)
+		}
+		defer audit.Close()
+	}
+
+	maxAffectedDocs, err := strconv.Atoi(*maxAffected)
+	if err != nil || maxAffectedDocs < 0 {
+		log.Fatalf("Invalid max affected documents %q: must be a non-negative integer", *maxAffected)
+	}
+
+	maxSessionWatches, err := strconv.Atoi(*maxWatches)
+	if err != nil || maxSessionWatches < 0 {
+		log.Fatalf("Invalid max watches %q: must be a non-negative integer", *maxWatches)
+	}
+
+	timeouts, err := loadTimeouts(*queryTimeout)
+	if err != nil {
+		log.Fatalf("Invalid query timeout: %v", err)
+	}
+
 	// Create RethinkDB server handler
-	rdbServer := server.NewRethinkDBServer(session)
+	rdbServer := server.NewRethinkDBServer(session,
+		server.WithReadOnly(*readOnly),
+		server.WithPolicy(policy),
+		server.WithReQLPermissions(server.ReQLPermissions{
+			JavaScript: envBool("MCP_REQL_ALLOW_JS"),
+			HTTP:       envBool("MCP_REQL_ALLOW_HTTP"),
+			Writes:     envBool("MCP_REQL_ALLOW_WRITES"),
+		}),
+		server.WithTimeouts(timeouts),
+		server.WithAdmin(*admin),
+		server.WithTableSchemas(schemas),
+		server.WithSchemaTable(*schemaTable),
+		server.WithMaxAffected(maxAffectedDocs),
+		server.WithMaxWatches(maxSessionWatches),
+		server.WithAuditFile(audit),
+		server.WithAuditTable(*auditTable),
+	)
+	if err := rdbServer.PrepareAuditLog(context.Background()); err != nil {
+		log.Fatalf("Failed to prepare audit table: %v", err)
+	}
+	if *readOnly {
+		fmt.Fprintf(os.Stderr, "Running in read-only mode\n")
+	} else if *admin {
+		fmt.Fprintf(os.Stderr, "Administration tools enabled\n")
+	}
 
 	// Create MCP server
 	mcpServer := mcp.NewServer(&mcp.Implementation{
 		Name:    "mcp-rethinkdb-server",
 		Version: "1.0.0",
-	}, nil)
+	}, &mcp.ServerOptions{
+		SubscribeHandler:   rdbServer.Subscribe,
+		UnsubscribeHandler: rdbServer.Unsubscribe,
+		HasResources:       true,
+	})
 
 	// Register all tools
 	rdbServer.RegisterTools(mcpServer)
+	defer rdbServer.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch *transport {
+	case "stdio":
+		// Run server over stdio
+		if err := mcpServer.Run(ctx, &mcp.StdioTransport{}); err != nil && !errors.Is(err, context.Canceled) {
+			log.Fatalf("Server error: %v", err)
+		}
+	case "http":
+		if err := serveHTTP(ctx, mcpServer, *listen); err != nil {
+			log.Fatalf("Server error: %v", err)
+		}
+	}
+}
+
+// serveHTTP serves mcpServer over the streamable HTTP transport on addr until
+// ctx is cancelled, then shuts down gracefully. Every client session shares
+// the same MCP server and therefore the same tool set and RethinkDB session.
+func serveHTTP(ctx context.Context, mcpServer *mcp.Server, addr string) error {
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server {
+		return mcpServer
+	}, nil)
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		fmt.Fprintf(os.Stderr, "Serving MCP over streamable HTTP on %s\n", addr)
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintf(os.Stderr, "Shutting down HTTP server\n")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down HTTP server: %w", err)
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// envOrDefault returns the value of the environment variable key, or def
+// when it is unset or empty.
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envBool reports whether the environment variable key is set to a true
+// value as understood by strconv.ParseBool.
+func envBool(key string) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	return err == nil && v
+}
+
+// loadPolicy builds the access policy from the optional policy file and the
+// comma-separated MCP_ALLOW_DATABASES, MCP_DENY_DATABASES, MCP_ALLOW_TABLES
+// and MCP_DENY_TABLES environment variables, which extend the file's lists.
+// It returns nil when no policy is configured.
+func loadPolicy(filename string) (*server.Policy, error) {
+	policy := &server.Policy{}
+	if filename != "" {
+		var err error
+		if policy, err = server.LoadPolicy(filename); err != nil {
+			return nil, err
+		}
+	}
+
+	policy.AllowDatabases = append(policy.AllowDatabases, envList("MCP_ALLOW_DATABASES")...)
+	policy.DenyDatabases = append(policy.DenyDatabases, envList("MCP_DENY_DATABASES")...)
+	policy.AllowTables = append(policy.AllowTables, envList("MCP_ALLOW_TABLES")...)
+	policy.DenyTables = append(policy.DenyTables, envList("MCP_DENY_TABLES")...)
+
+	if filename == "" && len(policy.AllowDatabases)+len(policy.DenyDatabases)+len(policy.AllowTables)+len(policy.DenyTables) == 0 {
+		return nil, nil
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// loadTimeouts builds the query timeouts from the default duration and the
+// comma-separated MCP_TOOL_TIMEOUTS environment variable, whose entries have
+// the form tool=duration, e.g. "aggregate=2m,query_table=10s".
+func loadTimeouts(def string) (server.Timeouts, error) {
+	var timeouts server.Timeouts
+	if def != "" {
+		d, err := time.ParseDuration(def)
+		if err != nil {
+			return server.Timeouts{}, err
+		}
+		timeouts.Default = d
+	}
+	for _, item := range envList("MCP_TOOL_TIMEOUTS") {
+		tool, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return server.Timeouts{}, fmt.Errorf("MCP_TOOL_TIMEOUTS entry %q must have the form tool=duration", item)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return server.Timeouts{}, fmt.Errorf("MCP_TOOL_TIMEOUTS entry %q: %w", item, err)
+		}
+		if timeouts.PerTool == nil {
+			timeouts.PerTool = make(map[string]time.Duration)
+		}
+		timeouts.PerTool[strings.TrimSpace(tool)] = d
+	}
+	return timeouts, nil
+}
 
-	// Run server over stdio
-	if err := mcpServer.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
-		log.Fatalf("Server error: %v", err)
+// envList splits the comma-separated environment variable key into its
+// non-empty, trimmed elements.
+func envList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
 	}
+	return list
 }
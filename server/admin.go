@@ -0,0 +1,399 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ErrAdminDisabled is returned by the administration tools when the server
+// is not running in admin mode.
+var ErrAdminDisabled = errors.New("administration tools are disabled")
+
+// confirmTTL is how long a drop confirmation token stays valid.
+const confirmTTL = 5 * time.Minute
+
+// pendingDrop is a drop that has been previewed and awaits confirmation.
+type pendingDrop struct {
+	tool     string
+	database string
+	table    string
+	expires  time.Time
+}
+
+// ─── Input/Output structs ────────────────────────────────────────────────────
+
+type DBCreateInput struct {
+	Database  string `json:"database" jsonschema:"Name of the database to create"`
+	TimeoutMs int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+type DBCreateOutput struct {
+	Database string `json:"database"`
+	Created  bool   `json:"created"`
+}
+
+type DBDropInput struct {
+	Database  string `json:"database" jsonschema:"Name of the database to drop"`
+	Confirm   string `json:"confirm,omitempty" jsonschema:"Confirmation token returned by a previous db_drop call for the same database; omit it to get a token"`
+	TimeoutMs int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+type TableCreateInput struct {
+	Database   string `json:"database" jsonschema:"The database name"`
+	Table      string `json:"table" jsonschema:"Name of the table to create"`
+	PrimaryKey string `json:"primary_key,omitempty" jsonschema:"Primary key field (default id)"`
+	Shards     int    `json:"shards,omitempty" jsonschema:"Number of shards (default 1)"`
+	Replicas   int    `json:"replicas,omitempty" jsonschema:"Number of replicas per shard (default 1)"`
+	Durability string `json:"durability,omitempty" jsonschema:"Write durability: hard or soft (default hard)"`
+	TimeoutMs  int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+type TableCreateOutput struct {
+	Database   string `json:"database"`
+	Table      string `json:"table"`
+	PrimaryKey string `json:"primary_key"`
+	Created    bool   `json:"created"`
+}
+
+type TableDropInput struct {
+	Database  string `json:"database" jsonschema:"The database name"`
+	Table     string `json:"table" jsonschema:"Name of the table to drop"`
+	Confirm   string `json:"confirm,omitempty" jsonschema:"Confirmation token returned by a previous table_drop call for the same table; omit it to get a token"`
+	TimeoutMs int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+// DropOutput reports a drop. Without a confirmation token nothing is
+// dropped: the output describes what would be dropped and carries the token
+// to pass back as confirm.
+type DropOutput struct {
+	Database     string   `json:"database"`
+	Table        string   `json:"table,omitempty"`
+	Dropped      bool     `json:"dropped"`
+	Tables       []string `json:"tables,omitempty"`
+	DocCount     int      `json:"doc_count,omitempty"`
+	ConfirmToken string   `json:"confirm_token,omitempty"`
+	ExpiresAt    string   `json:"expires_at,omitempty"`
+	Message      string   `json:"message"`
+}
+
+type TableRenameInput struct {
+	Database  string `json:"database" jsonschema:"The database name"`
+	Table     string `json:"table" jsonschema:"Current table name"`
+	NewName   string `json:"new_name" jsonschema:"New table name"`
+	TimeoutMs int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+type TableRenameOutput struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	NewName  string `json:"new_name"`
+	Renamed  bool   `json:"renamed"`
+}
+
+// ─── Handlers ────────────────────────────────────────────────────────────────
+
+func (s *RethinkDBServer) DBCreate(ctx context.Context, req *mcp.CallToolRequest, input DBCreateInput) (*mcp.CallToolResult, DBCreateOutput, error) {
+	if err := s.checkAdmin("db_create"); err != nil {
+		return nil, DBCreateOutput{}, err
+	}
+	if input.Database == "" {
+		return nil, DBCreateOutput{}, fmt.Errorf("database name is required")
+	}
+	if err := s.checkDatabase(input.Database); err != nil {
+		return nil, DBCreateOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "db_create", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("db_create of %s", input.Database)
+
+	resp, err := r.DBCreate(input.Database).RunWrite(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, DBCreateOutput{}, queryError(ctx, what, fmt.Errorf("failed to create database: %w", err))
+	}
+
+	return nil, DBCreateOutput{
+		Database: input.Database,
+		Created:  resp.DBsCreated == 1,
+	}, nil
+}
+
+func (s *RethinkDBServer) DBDrop(ctx context.Context, req *mcp.CallToolRequest, input DBDropInput) (*mcp.CallToolResult, DropOutput, error) {
+	if err := s.checkAdmin("db_drop"); err != nil {
+		return nil, DropOutput{}, err
+	}
+	if input.Database == "" {
+		return nil, DropOutput{}, fmt.Errorf("database name is required")
+	}
+	if err := s.checkDatabase(input.Database); err != nil {
+		return nil, DropOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "db_drop", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("db_drop of %s", input.Database)
+
+	// Dropping a database drops every table in it, so each one must be
+	// allowed. The error does not name the denied tables, which list_tables
+	// hides too.
+	cursor, err := r.DB(input.Database).TableList().Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, DropOutput{}, queryError(ctx, what, fmt.Errorf("failed to list tables: %w", err))
+	}
+	defer cursor.Close()
+	var tables []string
+	if err := cursor.All(&tables); err != nil {
+		return nil, DropOutput{}, queryError(ctx, what, fmt.Errorf("failed to read tables: %w", err))
+	}
+	for _, table := range tables {
+		if !s.policy.TableAllowed(input.Database, table) {
+			return nil, DropOutput{}, fmt.Errorf("%w: database %q holds tables outside the access policy", ErrAccessDenied, input.Database)
+		}
+	}
+	if err := s.checkReservedTables(input.Database, tables...); err != nil {
+		return nil, DropOutput{}, err
+	}
+
+	if input.Confirm == "" {
+		token, expires, err := s.requestConfirmation("db_drop", input.Database, "")
+		if err != nil {
+			return nil, DropOutput{}, err
+		}
+		return nil, DropOutput{
+			Database:     input.Database,
+			Tables:       tables,
+			ConfirmToken: token,
+			ExpiresAt:    expires.Format(time.RFC3339),
+			Message:      fmt.Sprintf("Database %q and its %d tables will be dropped. Call db_drop again with confirm set to the token to proceed.", input.Database, len(tables)),
+		}, nil
+	}
+
+	if err := s.consumeConfirmation(input.Confirm, "db_drop", input.Database, ""); err != nil {
+		return nil, DropOutput{}, err
+	}
+
+	resp, err := r.DBDrop(input.Database).RunWrite(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, DropOutput{}, queryError(ctx, what, fmt.Errorf("failed to drop database: %w", err))
+	}
+
+	return nil, DropOutput{
+		Database: input.Database,
+		Dropped:  resp.DBsDropped == 1,
+		Message:  fmt.Sprintf("Dropped database %q and %d tables.", input.Database, resp.TablesDropped),
+	}, nil
+}
+
+func (s *RethinkDBServer) TableCreate(ctx context.Context, req *mcp.CallToolRequest, input TableCreateInput) (*mcp.CallToolResult, TableCreateOutput, error) {
+	if err := s.checkAdmin("table_create"); err != nil {
+		return nil, TableCreateOutput{}, err
+	}
+	if input.Database == "" || input.Table == "" {
+		return nil, TableCreateOutput{}, fmt.Errorf("database and table names are required")
+	}
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, TableCreateOutput{}, err
+	}
+	if err := s.checkReservedTables(input.Database, input.Table); err != nil {
+		return nil, TableCreateOutput{}, err
+	}
+
+	opts := r.TableCreateOpts{}
+	pk := "id"
+	if input.PrimaryKey != "" {
+		pk = input.PrimaryKey
+		opts.PrimaryKey = input.PrimaryKey
+	}
+	if input.Shards < 0 || input.Replicas < 0 {
+		return nil, TableCreateOutput{}, fmt.Errorf("shards and replicas must be positive")
+	}
+	if input.Shards > 0 {
+		opts.Shards = input.Shards
+	}
+	if input.Replicas > 0 {
+		opts.Replicas = input.Replicas
+	}
+	switch input.Durability {
+	case "":
+	case "hard", "soft":
+		opts.Durability = input.Durability
+	default:
+		return nil, TableCreateOutput{}, fmt.Errorf("invalid durability %q: must be hard or soft", input.Durability)
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "table_create", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("table_create of %s.%s", input.Database, input.Table)
+
+	resp, err := r.DB(input.Database).TableCreate(input.Table, opts).RunWrite(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, TableCreateOutput{}, queryError(ctx, what, fmt.Errorf("failed to create table: %w", err))
+	}
+
+	return nil, TableCreateOutput{
+		Database:   input.Database,
+		Table:      input.Table,
+		PrimaryKey: pk,
+		Created:    resp.TablesCreated == 1,
+	}, nil
+}
+
+func (s *RethinkDBServer) TableDrop(ctx context.Context, req *mcp.CallToolRequest, input TableDropInput) (*mcp.CallToolResult, DropOutput, error) {
+	if err := s.checkAdmin("table_drop"); err != nil {
+		return nil, DropOutput{}, err
+	}
+	if input.Database == "" || input.Table == "" {
+		return nil, DropOutput{}, fmt.Errorf("database and table names are required")
+	}
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, DropOutput{}, err
+	}
+	if err := s.checkReservedTables(input.Database, input.Table); err != nil {
+		return nil, DropOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "table_drop", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("table_drop of %s.%s", input.Database, input.Table)
+
+	if input.Confirm == "" {
+		cursor, err := r.DB(input.Database).Table(input.Table).Count().Run(s.session, r.RunOpts{Context: ctx})
+		if err != nil {
+			return nil, DropOutput{}, queryError(ctx, what, fmt.Errorf("failed to count documents: %w", err))
+		}
+		defer cursor.Close()
+		var count int
+		if err := cursor.One(&count); err != nil {
+			return nil, DropOutput{}, queryError(ctx, what, fmt.Errorf("failed to read document count: %w", err))
+		}
+
+		token, expires, err := s.requestConfirmation("table_drop", input.Database, input.Table)
+		if err != nil {
+			return nil, DropOutput{}, err
+		}
+		return nil, DropOutput{
+			Database:     input.Database,
+			Table:        input.Table,
+			DocCount:     count,
+			ConfirmToken: token,
+			ExpiresAt:    expires.Format(time.RFC3339),
+			Message:      fmt.Sprintf("Table %q.%q and its %d documents will be dropped. Call table_drop again with confirm set to the token to proceed.", input.Database, input.Table, count),
+		}, nil
+	}
+
+	if err := s.consumeConfirmation(input.Confirm, "table_drop", input.Database, input.Table); err != nil {
+		return nil, DropOutput{}, err
+	}
+
+	resp, err := r.DB(input.Database).TableDrop(input.Table).RunWrite(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, DropOutput{}, queryError(ctx, what, fmt.Errorf("failed to drop table: %w", err))
+	}
+
+	return nil, DropOutput{
+		Database: input.Database,
+		Table:    input.Table,
+		Dropped:  resp.TablesDropped == 1,
+		Message:  fmt.Sprintf("Dropped table %q.%q.", input.Database, input.Table),
+	}, nil
+}
+
+// TableRename renames a table by updating its row in rethinkdb.table_config.
+func (s *RethinkDBServer) TableRename(ctx context.Context, req *mcp.CallToolRequest, input TableRenameInput) (*mcp.CallToolResult, TableRenameOutput, error) {
+	if err := s.checkAdmin("table_rename"); err != nil {
+		return nil, TableRenameOutput{}, err
+	}
+	if input.Database == "" || input.Table == "" || input.NewName == "" {
+		return nil, TableRenameOutput{}, fmt.Errorf("database, table and new_name are required")
+	}
+	if err := s.checkTables(input.Database, input.Table, input.NewName); err != nil {
+		return nil, TableRenameOutput{}, err
+	}
+	if err := s.checkReservedTables(input.Database, input.Table, input.NewName); err != nil {
+		return nil, TableRenameOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "table_rename", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("table_rename of %s.%s", input.Database, input.Table)
+
+	result, err := s.runWrite(ctx, r.DB("rethinkdb").Table("table_config").
+		Filter(map[string]interface{}{"db": input.Database, "name": input.Table}).
+		Update(map[string]interface{}{"name": input.NewName}))
+	if err != nil {
+		return nil, TableRenameOutput{}, queryError(ctx, what, fmt.Errorf("failed to rename table: %w", err))
+	}
+	if result.Errors > 0 {
+		return nil, TableRenameOutput{}, fmt.Errorf("failed to rename table: %s", result.FirstError)
+	}
+	if result.Replaced == 0 {
+		return nil, TableRenameOutput{}, fmt.Errorf("table %q.%q does not exist", input.Database, input.Table)
+	}
+
+	return nil, TableRenameOutput{
+		Database: input.Database,
+		Table:    input.Table,
+		NewName:  input.NewName,
+		Renamed:  true,
+	}, nil
+}
+
+// ─── Admin helpers ───────────────────────────────────────────────────────────
+
+// checkAdmin rejects administration tools unless admin mode is on and the
+// server is writable.
+func (s *RethinkDBServer) checkAdmin(tool string) error {
+	if !s.admin {
+		return fmt.Errorf("%w: %s requires admin mode", ErrAdminDisabled, tool)
+	}
+	if s.readOnly {
+		return fmt.Errorf("%w: %s is disabled", ErrReadOnly, tool)
+	}
+	return nil
+}
+
+// requestConfirmation records a pending drop and returns the token that
+// confirms it.
+func (s *RethinkDBServer) requestConfirmation(tool, database, table string) (string, time.Time, error) {
+	token, err := randomID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expires := time.Now().Add(confirmTTL)
+
+	s.confirmMu.Lock()
+	defer s.confirmMu.Unlock()
+	if s.confirmations == nil {
+		s.confirmations = make(map[string]pendingDrop)
+	}
+	for t, pending := range s.confirmations {
+		if time.Now().After(pending.expires) {
+			delete(s.confirmations, t)
+		}
+	}
+	s.confirmations[token] = pendingDrop{tool: tool, database: database, table: table, expires: expires}
+	return token, expires, nil
+}
+
+// consumeConfirmation checks that token confirms the given drop and
+// invalidates it.
+func (s *RethinkDBServer) consumeConfirmation(token, tool, database, table string) error {
+	s.confirmMu.Lock()
+	defer s.confirmMu.Unlock()
+
+	pending, ok := s.confirmations[token]
+	if !ok || time.Now().After(pending.expires) {
+		delete(s.confirmations, token)
+		return fmt.Errorf("invalid or expired confirmation token; call %s without confirm to get a new one", tool)
+	}
+	if pending.tool != tool || pending.database != database || pending.table != table {
+		return fmt.Errorf("confirmation token was issued for a different %s call", tool)
+	}
+	delete(s.confirmations, token)
+	return nil
+}
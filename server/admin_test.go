@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── administration tools ───────────────────────────────────────────────────
+
+const testAdminDB = "mcp_test_admin_db"
+
+func newAdminServer() *RethinkDBServer {
+	return NewRethinkDBServer(testSession, WithAdmin(true))
+}
+
+func TestAdmin_DisabledByDefault(t *testing.T) {
+	srv := newTestServer()
+	_, _, err := srv.DBCreate(context.Background(), &mcp.CallToolRequest{}, DBCreateInput{Database: testAdminDB})
+	if !errors.Is(err, ErrAdminDisabled) {
+		t.Fatalf("expected ErrAdminDisabled, got %v", err)
+	}
+}
+
+func TestAdmin_ReadOnlyWins(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithAdmin(true), WithReadOnly(true))
+	_, _, err := srv.TableCreate(context.Background(), &mcp.CallToolRequest{}, TableCreateInput{
+		Database: testDB,
+		Table:    "never_created",
+	})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestAdmin_DatabaseLifecycle(t *testing.T) {
+	srv := newAdminServer()
+	ctx := context.Background()
+	defer r.DBDrop(testAdminDB).RunWrite(testSession)
+
+	_, created, err := srv.DBCreate(ctx, &mcp.CallToolRequest{}, DBCreateInput{Database: testAdminDB})
+	if err != nil {
+		t.Fatalf("db_create failed: %v", err)
+	}
+	if !created.Created {
+		t.Error("expected the database to be created")
+	}
+
+	_, table, err := srv.TableCreate(ctx, &mcp.CallToolRequest{}, TableCreateInput{
+		Database:   testAdminDB,
+		Table:      "accounts",
+		PrimaryKey: "email",
+		Durability: "soft",
+	})
+	if err != nil {
+		t.Fatalf("table_create failed: %v", err)
+	}
+	if !table.Created || table.PrimaryKey != "email" {
+		t.Errorf("unexpected table_create output: %+v", table)
+	}
+
+	_, renamed, err := srv.TableRename(ctx, &mcp.CallToolRequest{}, TableRenameInput{
+		Database: testAdminDB,
+		Table:    "accounts",
+		NewName:  "users",
+	})
+	if err != nil {
+		t.Fatalf("table_rename failed: %v", err)
+	}
+	if !renamed.Renamed {
+		t.Error("expected the table to be renamed")
+	}
+
+	_, info, err := srv.TableInfo(ctx, &mcp.CallToolRequest{}, TableInfoInput{Database: testAdminDB, Table: "users"})
+	if err != nil {
+		t.Fatalf("renamed table not found: %v", err)
+	}
+	if info.PrimaryKey != "email" {
+		t.Errorf("expected primary key email, got %q", info.PrimaryKey)
+	}
+
+	_, preview, err := srv.TableDrop(ctx, &mcp.CallToolRequest{}, TableDropInput{Database: testAdminDB, Table: "users"})
+	if err != nil {
+		t.Fatalf("table_drop preview failed: %v", err)
+	}
+	if preview.Dropped || preview.ConfirmToken == "" {
+		t.Fatalf("expected a preview with a confirm token, got %+v", preview)
+	}
+
+	_, dropped, err := srv.TableDrop(ctx, &mcp.CallToolRequest{}, TableDropInput{
+		Database: testAdminDB,
+		Table:    "users",
+		Confirm:  preview.ConfirmToken,
+	})
+	if err != nil {
+		t.Fatalf("table_drop failed: %v", err)
+	}
+	if !dropped.Dropped {
+		t.Error("expected the table to be dropped")
+	}
+
+	_, dbPreview, err := srv.DBDrop(ctx, &mcp.CallToolRequest{}, DBDropInput{Database: testAdminDB})
+	if err != nil {
+		t.Fatalf("db_drop preview failed: %v", err)
+	}
+	_, dbDropped, err := srv.DBDrop(ctx, &mcp.CallToolRequest{}, DBDropInput{
+		Database: testAdminDB,
+		Confirm:  dbPreview.ConfirmToken,
+	})
+	if err != nil {
+		t.Fatalf("db_drop failed: %v", err)
+	}
+	if !dbDropped.Dropped {
+		t.Error("expected the database to be dropped")
+	}
+}
+
+func TestAdmin_ConfirmationTokens(t *testing.T) {
+	srv := newAdminServer()
+	ctx := context.Background()
+
+	_, preview, err := srv.TableDrop(ctx, &mcp.CallToolRequest{}, TableDropInput{Database: testDB, Table: testTable})
+	if err != nil {
+		t.Fatalf("table_drop preview failed: %v", err)
+	}
+	if preview.DocCount == 0 {
+		t.Error("expected the preview to report the document count")
+	}
+
+	// A token only confirms the drop it was issued for.
+	_, _, err = srv.TableDrop(ctx, &mcp.CallToolRequest{}, TableDropInput{
+		Database: testDB,
+		Table:    testJoinTable,
+		Confirm:  preview.ConfirmToken,
+	})
+	if err == nil {
+		t.Fatal("expected a token for another table to be rejected")
+	}
+
+	_, _, err = srv.TableDrop(ctx, &mcp.CallToolRequest{}, TableDropInput{
+		Database: testDB,
+		Table:    testTable,
+		Confirm:  "not-a-token",
+	})
+	if err == nil {
+		t.Fatal("expected an unknown token to be rejected")
+	}
+
+	_, info, err := srv.TableInfo(ctx, &mcp.CallToolRequest{}, TableInfoInput{Database: testDB, Table: testTable})
+	if err != nil || info.DocCount == 0 {
+		t.Fatalf("expected the test table to survive, got %+v, %v", info, err)
+	}
+}
+
+func TestAdmin_DBDropRefusesDeniedTables(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithAdmin(true), WithPolicy(&Policy{DenyTables: []string{"*.secrets"}}))
+	ctx := context.Background()
+	r.DBCreate(testAdminDB).RunWrite(testSession)
+	defer r.DBDrop(testAdminDB).RunWrite(testSession)
+	r.DB(testAdminDB).TableCreate("public").RunWrite(testSession)
+	r.DB(testAdminDB).TableCreate("secrets").RunWrite(testSession)
+
+	_, preview, err := srv.DBDrop(ctx, &mcp.CallToolRequest{}, DBDropInput{Database: testAdminDB})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("expected ErrAccessDenied, got %v", err)
+	}
+	if preview.ConfirmToken != "" || strings.Contains(err.Error(), "secrets") {
+		t.Errorf("expected no token and no denied table names, got %+v, %v", preview, err)
+	}
+}
@@ -0,0 +1,397 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+const (
+	// defaultAuditEntries is how many entries list_audit_log returns by
+	// default.
+	defaultAuditEntries = 50
+	// maxAuditEntries caps the limit a client may request.
+	maxAuditEntries = 500
+)
+
+// AuditEntry records one write_data call: who made it, what was asked, and
+// what changed.
+type AuditEntry struct {
+	ID        string       `json:"id,omitempty" rethinkdb:"id,omitempty"`
+	Time      time.Time    `json:"time" rethinkdb:"time"`
+	Client    *AuditClient `json:"client,omitempty" rethinkdb:"client,omitempty"`
+	Database  string       `json:"database" rethinkdb:"database"`
+	Table     string       `json:"table" rethinkdb:"table"`
+	Operation string       `json:"operation" rethinkdb:"operation"`
+	DryRun    bool         `json:"dry_run,omitempty" rethinkdb:"dry_run,omitempty"`
+	// Input is the write_data arguments as the client sent them.
+	Input interface{} `json:"input" rethinkdb:"input"`
+	// Keys are the primary keys of the documents the write changed, and
+	// Changes their values before and after.
+	Keys      []interface{} `json:"keys,omitempty" rethinkdb:"keys,omitempty"`
+	Changes   []WriteChange `json:"changes,omitempty" rethinkdb:"changes,omitempty"`
+	Inserted  int           `json:"inserted" rethinkdb:"inserted"`
+	Replaced  int           `json:"replaced" rethinkdb:"replaced"`
+	Unchanged int           `json:"unchanged" rethinkdb:"unchanged"`
+	Deleted   int           `json:"deleted" rethinkdb:"deleted"`
+	Errors    int           `json:"errors" rethinkdb:"errors"`
+	// Error is why the call failed or was refused, if it was.
+	Error string `json:"error,omitempty" rethinkdb:"error,omitempty"`
+}
+
+// AuditClient identifies the MCP client that made a call.
+type AuditClient struct {
+	Name      string `json:"name,omitempty" rethinkdb:"name,omitempty"`
+	Version   string `json:"version,omitempty" rethinkdb:"version,omitempty"`
+	SessionID string `json:"session_id,omitempty" rethinkdb:"session_id,omitempty"`
+}
+
+// auditFilter selects audit entries for list_audit_log.
+type auditFilter struct {
+	database  string
+	table     string
+	operation string
+	since     time.Time
+	// allowed hides entries for tables outside the access policy.
+	allowed func(db, table string) bool
+}
+
+func (f auditFilter) matches(entry AuditEntry) bool {
+	return (f.database == "" || entry.Database == f.database) &&
+		(f.table == "" || entry.Table == f.table) &&
+		(f.operation == "" || entry.Operation == f.operation) &&
+		(f.since.IsZero() || !entry.Time.Before(f.since)) &&
+		f.allowed(entry.Database, entry.Table)
+}
+
+// auditSink is where audit entries are appended and read back from.
+type auditSink interface {
+	append(ctx context.Context, entry AuditEntry) error
+	// list returns up to limit matching entries, newest first.
+	list(ctx context.Context, filter auditFilter, limit int) ([]AuditEntry, error)
+}
+
+// AuditFile is an audit log kept as JSON lines in a local file, one entry
+// per line. Entries are only ever appended.
+type AuditFile struct {
+	mu       sync.Mutex
+	filename string
+	file     *os.File
+}
+
+// OpenAuditFile opens the audit log at filename for appending, creating it
+// if needed.
+func OpenAuditFile(filename string) (*AuditFile, error) {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	return &AuditFile{filename: filename, file: file}, nil
+}
+
+// Close closes the audit file.
+func (f *AuditFile) Close() error {
+	return f.file.Close()
+}
+
+func (f *AuditFile) append(_ context.Context, entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit file: %w", err)
+	}
+	return nil
+}
+
+func (f *AuditFile) list(ctx context.Context, filter auditFilter, limit int) ([]AuditEntry, error) {
+	file, err := os.Open(f.filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit file: %w", err)
+	}
+	defer file.Close()
+
+	// Keep the last limit matches; the file is in the order calls were made.
+	var entries []AuditEntry
+	reader := bufio.NewReader(file)
+	for lineNo := 1; ; lineNo++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		line, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var entry AuditEntry
+			if jsonErr := json.Unmarshal(line, &entry); jsonErr != nil {
+				return nil, fmt.Errorf("audit file line %d: %w", lineNo, jsonErr)
+			}
+			if filter.matches(entry) {
+				entries = append(entries, entry)
+				if len(entries) > limit {
+					entries = entries[1:]
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit file: %w", err)
+		}
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// auditTable is an audit log kept in a RethinkDB table named
+// "database.table". No tool may change it, and run_reql may not read it.
+type auditTable struct {
+	session *r.Session
+	name    string
+}
+
+// auditTimeIndex is the secondary index list_audit_log reads the audit
+// table through, newest first.
+const auditTimeIndex = "time"
+
+func (t *auditTable) term() r.Term {
+	db, table, _ := strings.Cut(t.name, ".")
+	return r.DB(db).Table(table)
+}
+
+// prepare creates the time index on the audit table if it is missing and
+// waits until it is ready.
+func (t *auditTable) prepare(ctx context.Context) error {
+	cursor, err := t.term().IndexList().Run(t.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return fmt.Errorf("failed to list indexes of audit table %s: %w", t.name, err)
+	}
+	var indexes []string
+	err = cursor.All(&indexes)
+	cursor.Close()
+	if err != nil {
+		return fmt.Errorf("failed to list indexes of audit table %s: %w", t.name, err)
+	}
+	if !containsString(indexes, auditTimeIndex) {
+		if _, err := t.term().IndexCreate(auditTimeIndex).RunWrite(t.session, r.RunOpts{Context: ctx}); err != nil {
+			return fmt.Errorf("failed to create the %s index on audit table %s: %w", auditTimeIndex, t.name, err)
+		}
+	}
+	cursor, err = t.term().IndexWait(auditTimeIndex).Run(t.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return fmt.Errorf("failed to wait for the %s index on audit table %s: %w", auditTimeIndex, t.name, err)
+	}
+	return cursor.Close()
+}
+
+func (t *auditTable) append(ctx context.Context, entry AuditEntry) error {
+	if _, err := t.term().Insert(entry).RunWrite(t.session, r.RunOpts{Context: ctx}); err != nil {
+		return fmt.Errorf("failed to write audit table %s: %w", t.name, err)
+	}
+	return nil
+}
+
+func (t *auditTable) list(ctx context.Context, filter auditFilter, limit int) ([]AuditEntry, error) {
+	query := t.term()
+	if !filter.since.IsZero() {
+		query = query.Between(filter.since, r.MaxVal, r.BetweenOpts{Index: auditTimeIndex})
+	}
+	query = query.OrderBy(r.OrderByOpts{Index: r.Desc(auditTimeIndex)}).Filter(func(row r.Term) r.Term {
+		conds := []interface{}{true}
+		if filter.database != "" {
+			conds = append(conds, row.Field("database").Eq(filter.database))
+		}
+		if filter.table != "" {
+			conds = append(conds, row.Field("table").Eq(filter.table))
+		}
+		if filter.operation != "" {
+			conds = append(conds, row.Field("operation").Eq(filter.operation))
+		}
+		return r.And(conds...)
+	})
+
+	// The access policy cannot be expressed in ReQL, so entries are read a
+	// page of limit at a time and checked until enough are allowed.
+	var entries []AuditEntry
+	for skip := 0; len(entries) < limit; skip += limit {
+		cursor, err := query.Skip(skip).Limit(limit).Run(t.session, r.RunOpts{Context: ctx})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit table %s: %w", t.name, err)
+		}
+		var page []AuditEntry
+		err = cursor.All(&page)
+		cursor.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit table %s: %w", t.name, err)
+		}
+		for _, entry := range page {
+			if len(entries) < limit && filter.allowed(entry.Database, entry.Table) {
+				entries = append(entries, entry)
+			}
+		}
+		if len(page) < limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// PrepareAuditLog sets up the audit log for list_audit_log: when it is a
+// table, the time index entries are read through is created if missing.
+func (s *RethinkDBServer) PrepareAuditLog(ctx context.Context) error {
+	if t, ok := s.audit.(*auditTable); ok {
+		return t.prepare(ctx)
+	}
+	return nil
+}
+
+// isAuditTable reports whether db.table holds the audit log.
+func (s *RethinkDBServer) isAuditTable(db, table string) bool {
+	t, ok := s.audit.(*auditTable)
+	return ok && t.name == db+"."+table
+}
+
+// recordWrite appends a write_data call to the audit log. A failure to
+// record is reported as a warning on the call's output, since the write
+// itself has already happened.
+func (s *RethinkDBServer) recordWrite(ctx context.Context, req *mcp.CallToolRequest, input WriteDataInput, output *WriteDataOutput, callErr error) {
+	entry := AuditEntry{
+		Time:      time.Now().UTC(),
+		Client:    auditClient(req),
+		Database:  input.Database,
+		Table:     input.Table,
+		Operation: output.Operation,
+		DryRun:    input.DryRun,
+		Changes:   output.changes,
+		Inserted:  output.Inserted,
+		Replaced:  output.Replaced,
+		Unchanged: output.Unchanged,
+		Deleted:   output.Deleted,
+		Errors:    output.Errors,
+		Error:     output.FirstError,
+	}
+	if entry.Operation == "" {
+		entry.Operation = input.Operation
+	}
+	if callErr != nil {
+		entry.Error = callErr.Error()
+	}
+
+	// Store the arguments as a JSON value rather than raw bytes.
+	var args interface{}
+	if data, err := json.Marshal(input); err == nil && json.Unmarshal(data, &args) == nil {
+		entry.Input = args
+	}
+
+	if len(entry.Changes) > 0 {
+		pk, err := s.primaryKey(ctx, input.Database, input.Table)
+		if err == nil {
+			for _, change := range entry.Changes {
+				doc, _ := change.NewVal.(map[string]interface{})
+				if doc == nil {
+					doc, _ = change.OldVal.(map[string]interface{})
+				}
+				if key, ok := doc[pk]; ok {
+					entry.Keys = append(entry.Keys, key)
+				}
+			}
+		}
+	}
+
+	if err := s.audit.append(ctx, entry); err != nil {
+		output.Warnings = append(output.Warnings, "audit log: "+err.Error())
+	}
+}
+
+// auditClient identifies the client behind a tool call, when known.
+func auditClient(req *mcp.CallToolRequest) *AuditClient {
+	if req == nil || req.Session == nil {
+		return nil
+	}
+	client := &AuditClient{SessionID: req.Session.ID()}
+	if params := req.Session.InitializeParams(); params != nil && params.ClientInfo != nil {
+		client.Name = params.ClientInfo.Name
+		client.Version = params.ClientInfo.Version
+	}
+	return client
+}
+
+type ListAuditLogInput struct {
+	Database  string `json:"database,omitempty" jsonschema:"Only entries for this database"`
+	Table     string `json:"table,omitempty" jsonschema:"Only entries for this table"`
+	Operation string `json:"operation,omitempty" jsonschema:"Only entries for this write_data operation"`
+	Since     string `json:"since,omitempty" jsonschema:"Only entries at or after this RFC 3339 time"`
+	Limit     int    `json:"limit,omitempty" jsonschema:"Maximum number of entries, newest first (default 50, max 500)"`
+	TimeoutMs int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+type ListAuditLogOutput struct {
+	Entries []AuditEntry `json:"entries"`
+	Count   int          `json:"count"`
+}
+
+func (s *RethinkDBServer) ListAuditLog(ctx context.Context, req *mcp.CallToolRequest, input ListAuditLogInput) (*mcp.CallToolResult, ListAuditLogOutput, error) {
+	if s.audit == nil {
+		return nil, ListAuditLogOutput{}, fmt.Errorf("no audit log is configured")
+	}
+	if input.Database != "" {
+		if err := s.checkDatabase(input.Database); err != nil {
+			return nil, ListAuditLogOutput{}, err
+		}
+	}
+	if input.Database != "" && input.Table != "" {
+		if err := s.checkTables(input.Database, input.Table); err != nil {
+			return nil, ListAuditLogOutput{}, err
+		}
+	}
+
+	filter := auditFilter{
+		database:  input.Database,
+		table:     input.Table,
+		operation: input.Operation,
+		allowed: func(db, table string) bool {
+			return s.checkTables(db, table) == nil
+		},
+	}
+	if input.Since != "" {
+		since, err := time.Parse(time.RFC3339, input.Since)
+		if err != nil {
+			return nil, ListAuditLogOutput{}, fmt.Errorf("invalid since: %w", err)
+		}
+		filter.since = since
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultAuditEntries
+	}
+	if limit > maxAuditEntries {
+		limit = maxAuditEntries
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "list_audit_log", input.TimeoutMs)
+	defer cancel()
+
+	entries, err := s.audit.list(ctx, filter, limit)
+	if err != nil {
+		return nil, ListAuditLogOutput{}, queryError(ctx, "list_audit_log", err)
+	}
+	if entries == nil {
+		entries = []AuditEntry{}
+	}
+	return nil, ListAuditLogOutput{Entries: entries, Count: len(entries)}, nil
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── write_data audit log ───────────────────────────────────────────────────
+
+func openTestAuditFile(t *testing.T) *AuditFile {
+	t.Helper()
+	file, err := OpenAuditFile(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+func TestAuditFile_List(t *testing.T) {
+	file := openTestAuditFile(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, table := range []string{"users", "orders", "users", "secrets"} {
+		entry := AuditEntry{Time: start.Add(time.Duration(i) * time.Hour), Database: "app", Table: table, Operation: "insert", Inserted: i}
+		if err := file.append(context.Background(), entry); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	allowed := func(db, table string) bool { return table != "secrets" }
+	entries, err := file.list(context.Background(), auditFilter{table: "users", allowed: allowed}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Inserted != 2 || entries[1].Inserted != 0 {
+		t.Errorf("expected both users entries, newest first, got %+v", entries)
+	}
+
+	entries, err = file.list(context.Background(), auditFilter{since: start.Add(time.Hour), allowed: allowed}, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Table != "users" || entries[0].Inserted != 2 {
+		t.Errorf("expected the newest allowed entry, got %+v", entries)
+	}
+}
+
+func TestAuditTable_List(t *testing.T) {
+	r.DB(testDB).TableCreate("mcp_test_audit_list").RunWrite(testSession)
+	defer r.DB(testDB).TableDrop("mcp_test_audit_list").RunWrite(testSession)
+	srv := NewRethinkDBServer(testSession, WithAuditTable(testDB+".mcp_test_audit_list"))
+	if err := srv.PrepareAuditLog(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, table := range []string{"users", "secrets", "secrets", "orders", "users"} {
+		entry := AuditEntry{Time: start.Add(time.Duration(i) * time.Hour), Database: "app", Table: table, Operation: "insert", Inserted: i}
+		if err := srv.audit.append(context.Background(), entry); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	allowed := func(db, table string) bool { return table != "secrets" }
+	entries, err := srv.audit.list(context.Background(), auditFilter{allowed: allowed}, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 3 || entries[0].Inserted != 4 || entries[1].Inserted != 3 || entries[2].Inserted != 0 {
+		t.Errorf("expected the three newest allowed entries, got %+v", entries)
+	}
+
+	entries, err = srv.audit.list(context.Background(), auditFilter{since: start.Add(time.Hour), allowed: allowed}, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Inserted != 3 {
+		t.Errorf("expected the allowed entries since the second hour, got %+v", entries)
+	}
+}
+
+func TestWriteData_RecordsAuditEntry(t *testing.T) {
+	file := openTestAuditFile(t)
+	srv := NewRethinkDBServer(testSession, WithAuditFile(file))
+	defer r.DB(testDB).Table(testTable).Get("audit_1").Delete().RunWrite(testSession)
+
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database: testDB,
+		Table:    testTable,
+		Data:     json.RawMessage(`{"id": "audit_1", "name": "Audited"}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(output.Changes) != 0 || len(output.Warnings) != 0 {
+		t.Errorf("expected no changes or warnings in the output, got %+v", output)
+	}
+
+	_, list, err := srv.ListAuditLog(context.Background(), &mcp.CallToolRequest{}, ListAuditLogInput{Table: testTable})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if list.Count != 1 {
+		t.Fatalf("expected 1 entry, got %+v", list)
+	}
+	entry := list.Entries[0]
+	if entry.Operation != "insert" || entry.Inserted != 1 || len(entry.Keys) != 1 || entry.Keys[0] != "audit_1" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if len(entry.Changes) != 1 || entry.Changes[0].OldVal != nil {
+		t.Errorf("expected the inserted document as a change, got %+v", entry.Changes)
+	}
+	if input, _ := entry.Input.(map[string]interface{}); input["table"] != testTable {
+		t.Errorf("expected the tool input to be recorded, got %v", entry.Input)
+	}
+}
+
+func TestAuditTableIsAppendOnly(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithAuditTable(testDB+".mcp_test_audit"))
+	_, _, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     "mcp_test_audit",
+		Data:      json.RawMessage(`{}`),
+		Operation: "delete",
+	})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("expected writes to the audit table to be denied, got %v", err)
+	}
+
+	srv = NewRethinkDBServer(testSession, WithAuditTable(testDB+".mcp_test_audit"), WithAdmin(true))
+	_, _, err = srv.TableRename(context.Background(), &mcp.CallToolRequest{}, TableRenameInput{Database: testDB, Table: "mcp_test_audit", NewName: "renamed"})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("table_rename: expected ErrAccessDenied, got %v", err)
+	}
+	_, _, err = srv.IndexCreate(context.Background(), &mcp.CallToolRequest{}, IndexCreateInput{Database: testDB, Table: "mcp_test_audit", Index: "time"})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("index_create: expected ErrAccessDenied, got %v", err)
+	}
+}
+
+func TestAuditTableIsOnlyReadableThroughListAuditLog(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithAuditTable(testDB+".mcp_test_audit"))
+	_, _, err := srv.QueryTable(context.Background(), &mcp.CallToolRequest{}, QueryTableInput{Database: testDB, Table: "mcp_test_audit"})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("query_table: expected ErrAccessDenied, got %v", err)
+	}
+	_, _, err = srv.RunReQL(context.Background(), &mcp.CallToolRequest{}, RunReQLInput{Query: `r.db('` + testDB + `').table('mcp_test_audit').count()`})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("run_reql: expected ErrAccessDenied, got %v", err)
+	}
+}
+
+func TestListAuditLog_NotConfigured(t *testing.T) {
+	srv := newTestServer()
+	if _, _, err := srv.ListAuditLog(context.Background(), &mcp.CallToolRequest{}, ListAuditLogInput{}); err == nil {
+		t.Error("expected an error without an audit log")
+	}
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+const (
+	// defaultVersionField is the field compare_and_set checks when the
+	// caller gives an expected_version without a version_field.
+	defaultVersionField = "version"
+	// casConflict is the error the update function raises when the
+	// precondition fails, telling a conflict apart from other errors.
+	casConflict = "compare_and_set precondition failed"
+)
+
+// WriteConflict describes a compare_and_set whose precondition failed: the
+// document as it is now, with the version and hash to expect when retrying.
+type WriteConflict struct {
+	Key interface{} `json:"key"`
+	// Current is null when no document has the key.
+	Current interface{} `json:"current"`
+	Version interface{} `json:"version,omitempty"`
+	Hash    string      `json:"hash"`
+}
+
+// casPrecondition is what the stored document must match for a
+// compare_and_set to apply.
+type casPrecondition struct {
+	versionField string
+	version      interface{}
+	hasVersion   bool
+	hash         string
+}
+
+// parsePrecondition reads the expected version and hash from input. At least
+// one is required.
+func parsePrecondition(input WriteDataInput) (casPrecondition, error) {
+	pre := casPrecondition{versionField: input.VersionField, hash: input.ExpectedHash}
+	if pre.versionField == "" {
+		pre.versionField = defaultVersionField
+	}
+	if len(input.ExpectedVersion) > 0 {
+		if err := json.Unmarshal(input.ExpectedVersion, &pre.version); err != nil {
+			return casPrecondition{}, fmt.Errorf("invalid expected_version: %w", err)
+		}
+		pre.hasVersion = true
+	}
+	if !pre.hasVersion && pre.hash == "" {
+		return casPrecondition{}, fmt.Errorf("compare_and_set requires expected_version or expected_hash")
+	}
+	return pre, nil
+}
+
+// documentHash returns the hash compare_and_set compares expected_hash
+// with: a name-based UUID of the document's JSON, computed by RethinkDB so
+// that it can be checked atomically inside an update.
+func documentHash(doc r.Term) r.Term {
+	return r.UUID(doc.ToJSON())
+}
+
+// condition returns the ReQL test of the stored document row.
+func (pre casPrecondition) condition(row r.Term) r.Term {
+	var checks []interface{}
+	if pre.hasVersion {
+		checks = append(checks, row.Field(pre.versionField).Default(nil).Eq(pre.version))
+	}
+	if pre.hash != "" {
+		checks = append(checks, documentHash(row).Eq(pre.hash))
+	}
+	if len(checks) == 1 {
+		return checks[0].(r.Term)
+	}
+	return r.And(checks...)
+}
+
+// matches applies the precondition in Go to a document read with its hash,
+// for dry runs.
+func (pre casPrecondition) matches(doc map[string]interface{}, hash string) bool {
+	if pre.hasVersion && !sameValue(doc[pre.versionField], pre.version) {
+		return false
+	}
+	return pre.hash == "" || pre.hash == hash
+}
+
+// patch returns the fields to merge into the document. A numeric version is
+// incremented unless the caller sets the version field itself.
+func (pre casPrecondition) patch(doc map[string]interface{}, row r.Term) map[string]interface{} {
+	patch := make(map[string]interface{}, len(doc)+1)
+	for k, v := range doc {
+		patch[k] = v
+	}
+	if _, isNumber := pre.version.(float64); isNumber && pre.hasVersion {
+		if _, set := doc[pre.versionField]; !set {
+			patch[pre.versionField] = row.Field(pre.versionField).Add(1)
+		}
+	}
+	return patch
+}
+
+// compareAndSet updates a single document only if it still matches the
+// caller's expected version or hash. The check and the write happen in one
+// update function, so no other write can slip in between; when the check
+// fails the document is left as it is and returned in a conflict result.
+func (s *RethinkDBServer) compareAndSet(ctx context.Context, what string, input WriteDataInput, data interface{}) (*mcp.CallToolResult, WriteDataOutput, error) {
+	pre, err := parsePrecondition(input)
+	if err != nil {
+		return nil, WriteDataOutput{}, err
+	}
+	doc, isObject := data.(map[string]interface{})
+	if !isObject {
+		return nil, WriteDataOutput{}, fmt.Errorf("compare_and_set takes a single document")
+	}
+	pk, err := s.primaryKey(ctx, input.Database, input.Table)
+	if err != nil {
+		return nil, WriteDataOutput{}, queryError(ctx, what, err)
+	}
+	keys, err := documentKeys(pk, []interface{}{doc})
+	if err != nil {
+		return nil, WriteDataOutput{}, fmt.Errorf("invalid compare_and_set: %w", err)
+	}
+	key := keys[0]
+	table := r.DB(input.Database).Table(input.Table)
+
+	output := WriteDataOutput{
+		Database:  input.Database,
+		Table:     input.Table,
+		Operation: "compare_and_set",
+		DryRun:    input.DryRun,
+	}
+
+	if input.DryRun {
+		current, hash, err := s.currentDocument(ctx, table, key)
+		if err != nil {
+			return nil, WriteDataOutput{}, queryError(ctx, what, err)
+		}
+		preview := WritePreview{Key: key, Old: current}
+		switch {
+		case current == nil:
+			preview.Action = "not_found"
+			output.NotFound = []interface{}{key}
+		case !pre.matches(current, hash):
+			preview.Action = "conflict"
+			output.Conflict = &WriteConflict{Key: key, Current: current, Version: current[pre.versionField], Hash: hash}
+		default:
+			after := mergeDocument(current, doc)
+			if v, isNumber := current[pre.versionField].(float64); isNumber && pre.hasVersion {
+				if _, set := doc[pre.versionField]; !set {
+					after[pre.versionField] = v + 1
+				}
+			}
+			preview.New = after
+			if sameDocument(current, after) {
+				preview.Action = "unchanged"
+				output.Unchanged++
+			} else {
+				preview.Action = "replace"
+				output.Replaced++
+			}
+		}
+		output.Preview = []WritePreview{preview}
+		return nil, output, nil
+	}
+
+	query := table.Get(key).Update(func(row r.Term) interface{} {
+		return r.Branch(pre.condition(row), pre.patch(doc, row), r.Error(casConflict))
+	}, r.UpdateOpts{ReturnChanges: input.wantsChanges()})
+	result, err := s.runWrite(ctx, query)
+	if err != nil {
+		return nil, WriteDataOutput{}, queryError(ctx, what, fmt.Errorf("failed to execute compare_and_set: %w", err))
+	}
+
+	conflict := result.Errors > 0 && result.FirstError == casConflict
+	if conflict {
+		// The precondition failure is reported as a conflict, not a
+		// RethinkDB error.
+		result.Errors, result.FirstError = 0, ""
+	}
+	addWriteResult(&output, result, returnedChanges(input))
+	if result.Skipped > 0 {
+		output.NotFound = []interface{}{key}
+	}
+	if output.Errors > 0 {
+		return &mcp.CallToolResult{IsError: true}, output, nil
+	}
+	if !conflict {
+		return nil, output, nil
+	}
+
+	current, hash, err := s.currentDocument(ctx, table, key)
+	if err != nil {
+		return nil, WriteDataOutput{}, queryError(ctx, what, err)
+	}
+	output.Conflict = &WriteConflict{Key: key, Current: current, Hash: hash}
+	if current != nil {
+		output.Conflict.Version = current[pre.versionField]
+	}
+	return &mcp.CallToolResult{IsError: true}, output, nil
+}
+
+// currentDocument reads the document with the given key and its hash. The
+// document is nil when there is none.
+func (s *RethinkDBServer) currentDocument(ctx context.Context, table r.Term, key interface{}) (map[string]interface{}, string, error) {
+	doc := table.Get(key)
+	cursor, err := r.Expr(map[string]interface{}{
+		"doc":  doc,
+		"hash": documentHash(doc),
+	}).Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read current document: %w", err)
+	}
+	defer cursor.Close()
+	var result struct {
+		Doc  map[string]interface{} `rethinkdb:"doc"`
+		Hash string                 `rethinkdb:"hash"`
+	}
+	if err := cursor.One(&result); err != nil {
+		return nil, "", fmt.Errorf("failed to read current document: %w", err)
+	}
+	return result.Doc, result.Hash, nil
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── write_data compare_and_set ─────────────────────────────────────────────
+
+func TestParsePrecondition(t *testing.T) {
+	pre, err := parsePrecondition(WriteDataInput{ExpectedVersion: json.RawMessage(`3`)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pre.versionField != defaultVersionField || pre.version != 3.0 || !pre.hasVersion {
+		t.Errorf("unexpected precondition: %+v", pre)
+	}
+	if !pre.matches(map[string]interface{}{"version": 3}, "") || pre.matches(map[string]interface{}{"version": 4.0}, "") {
+		t.Error("expected only version 3 to match")
+	}
+
+	pre, err = parsePrecondition(WriteDataInput{ExpectedHash: "abc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !pre.matches(map[string]interface{}{}, "abc") || pre.matches(map[string]interface{}{}, "def") {
+		t.Error("expected only hash abc to match")
+	}
+
+	if _, err := parsePrecondition(WriteDataInput{}); err == nil {
+		t.Error("expected a missing precondition to be rejected")
+	}
+}
+
+func TestWriteData_CompareAndSet(t *testing.T) {
+	srv := newTestServer()
+	r.DB(testDB).Table(testTable).Insert(map[string]interface{}{
+		"id": "cas_1", "name": "Before", "version": 1,
+	}).RunWrite(testSession)
+	defer r.DB(testDB).Table(testTable).Get("cas_1").Delete().RunWrite(testSession)
+
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:        testDB,
+		Table:           testTable,
+		Data:            json.RawMessage(`{"id": "cas_1", "name": "After"}`),
+		Operation:       "compare_and_set",
+		ExpectedVersion: json.RawMessage(`1`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Replaced != 1 || output.Conflict != nil {
+		t.Fatalf("expected the update to apply, got %+v", output)
+	}
+
+	// The version was bumped to 2, so a second writer expecting 1 conflicts.
+	res, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:        testDB,
+		Table:           testTable,
+		Data:            json.RawMessage(`{"id": "cas_1", "name": "Clobbered"}`),
+		Operation:       "compare_and_set",
+		ExpectedVersion: json.RawMessage(`1`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res == nil || !res.IsError || output.Conflict == nil {
+		t.Fatalf("expected a conflict, got %+v", output)
+	}
+	current := output.Conflict.Current.(map[string]interface{})
+	if current["name"] != "After" || output.Conflict.Version != 2.0 || output.Conflict.Hash == "" {
+		t.Errorf("expected the current document at version 2, got %+v", output.Conflict)
+	}
+
+	// Retrying with the returned hash succeeds.
+	_, output, err = srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:     testDB,
+		Table:        testTable,
+		Data:         json.RawMessage(`{"id": "cas_1", "name": "Retried"}`),
+		Operation:    "compare_and_set",
+		ExpectedHash: output.Conflict.Hash,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Replaced != 1 || output.Conflict != nil {
+		t.Errorf("expected the retry to apply, got %+v", output)
+	}
+}
+
+func TestWriteData_CompareAndSetNotFound(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:        testDB,
+		Table:           testTable,
+		Data:            json.RawMessage(`{"id": "cas_missing", "name": "Nobody"}`),
+		Operation:       "compare_and_set",
+		ExpectedVersion: json.RawMessage(`1`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(output.NotFound) != 1 || output.Inserted != 0 {
+		t.Errorf("expected cas_missing to be reported, got %+v", output)
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+const (
+	// defaultMaxChanges is how many changes write_data returns by default
+	// when return_changes is set.
+	defaultMaxChanges = 20
+	// maxReturnedChanges caps the max_changes a client may request.
+	maxReturnedChanges = 1000
+)
+
+// WriteChange is one document changed by a write: its value before and
+// after, either of which is null for inserts and deletes.
+type WriteChange struct {
+	OldVal interface{} `json:"old_val" rethinkdb:"old_val"`
+	NewVal interface{} `json:"new_val" rethinkdb:"new_val"`
+}
+
+// writeResult is the response to a write query, including the warnings
+// that r.WriteResponse does not decode.
+type writeResult struct {
+	r.WriteResponse
+	Warnings []string `rethinkdb:"warnings"`
+}
+
+// runWrite runs a write query and decodes its response. Unlike RunWrite it
+// does not turn per-document errors into a Go error, so the counts and
+// changes of a partially applied write are kept.
+func (s *RethinkDBServer) runWrite(ctx context.Context, query r.Term) (writeResult, error) {
+	var result writeResult
+	cursor, err := query.Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return result, err
+	}
+	defer cursor.Close()
+	if err := cursor.One(&result); err != nil && err != r.ErrEmptyResult {
+		return result, err
+	}
+	return result, nil
+}
+
+// changeLimit returns how many changes to include for a requested
+// max_changes.
+func changeLimit(requested int) int {
+	switch {
+	case requested <= 0:
+		return defaultMaxChanges
+	case requested > maxReturnedChanges:
+		return maxReturnedChanges
+	}
+	return requested
+}
+
+// returnedChanges is how many changes the output of a write includes: none
+// unless return_changes is set.
+func returnedChanges(input WriteDataInput) int {
+	if !input.ReturnChanges {
+		return 0
+	}
+	return changeLimit(input.MaxChanges)
+}
+
+// wantsChanges reports whether the write must ask RethinkDB for changes,
+// because the caller or the audit log needs them.
+func (input WriteDataInput) wantsChanges() bool {
+	return input.ReturnChanges || input.auditChanges
+}
+
+// addWriteResult copies the counts, generated keys, warnings and up to limit
+// changes of a write response into output. Every change is kept for the
+// audit log.
+func addWriteResult(output *WriteDataOutput, result writeResult, limit int) {
+	output.Inserted = result.Inserted
+	output.Replaced = result.Replaced
+	output.Unchanged = result.Unchanged
+	output.Deleted = result.Deleted
+	output.Errors = result.Errors
+	output.FirstError = result.FirstError
+	output.GeneratedKeys = result.GeneratedKeys
+	output.Warnings = append(output.Warnings, result.Warnings...)
+
+	for i, change := range result.Changes {
+		c := WriteChange{OldVal: change.OldValue, NewVal: change.NewValue}
+		output.changes = append(output.changes, c)
+		switch {
+		case i < limit:
+			output.Changes = append(output.Changes, c)
+		case i == limit && limit > 0:
+			output.ChangesTruncated = true
+			output.Warnings = append(output.Warnings, fmt.Sprintf("returned %d of %d changes; raise max_changes (up to %d) to see more", limit, len(result.Changes), maxReturnedChanges))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── write_data changes ─────────────────────────────────────────────────────
+
+func TestChangeLimit(t *testing.T) {
+	tests := map[int]int{0: defaultMaxChanges, -1: defaultMaxChanges, 5: 5, 5000: maxReturnedChanges}
+	for requested, want := range tests {
+		if got := changeLimit(requested); got != want {
+			t.Errorf("changeLimit(%d) = %d, want %d", requested, got, want)
+		}
+	}
+}
+
+func TestAddWriteResult_TruncatesChanges(t *testing.T) {
+	var result writeResult
+	result.Deleted = 3
+	result.Warnings = []string{"Too many changes, array truncated to 100000."}
+	for i := 0; i < 3; i++ {
+		result.Changes = append(result.Changes, r.ChangeResponse{OldValue: map[string]interface{}{"id": i}})
+	}
+
+	var output WriteDataOutput
+	addWriteResult(&output, result, 2)
+	if output.Deleted != 3 || len(output.Changes) != 2 || !output.ChangesTruncated {
+		t.Errorf("expected 2 of 3 changes, got %+v", output)
+	}
+	if len(output.Warnings) != 2 {
+		t.Errorf("expected the server warning and a truncation warning, got %v", output.Warnings)
+	}
+}
+
+func TestWriteData_ReturnChangesInsert(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:      testDB,
+		Table:         testTable,
+		Data:          json.RawMessage(`{"name": "Generated"}`),
+		ReturnChanges: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(output.GeneratedKeys) != 1 {
+		t.Fatalf("expected a generated key, got %+v", output)
+	}
+	defer r.DB(testDB).Table(testTable).Get(output.GeneratedKeys[0]).Delete().RunWrite(testSession)
+
+	if len(output.Changes) != 1 || output.Changes[0].OldVal != nil {
+		t.Fatalf("expected one change without an old value, got %+v", output.Changes)
+	}
+	if doc := output.Changes[0].NewVal.(map[string]interface{}); doc["id"] != output.GeneratedKeys[0] {
+		t.Errorf("expected the new value to carry the generated key, got %v", doc)
+	}
+}
+
+func TestWriteData_ReturnChangesUpdate(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:      testDB,
+		Table:         testTable,
+		Data:          json.RawMessage(`{"id": "2", "age": 26}`),
+		Operation:     "update",
+		ReturnChanges: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer r.DB(testDB).Table(testTable).Get("2").Update(map[string]interface{}{"age": 25}).RunWrite(testSession)
+
+	if len(output.Changes) != 1 {
+		t.Fatalf("expected one change, got %+v", output.Changes)
+	}
+	oldVal := output.Changes[0].OldVal.(map[string]interface{})
+	newVal := output.Changes[0].NewVal.(map[string]interface{})
+	if oldVal["age"] != 25.0 || newVal["age"] != 26.0 {
+		t.Errorf("expected age to go from 25 to 26, got %v -> %v", oldVal["age"], newVal["age"])
+	}
+}
+
+func TestWriteData_InsertErrorsAreReported(t *testing.T) {
+	srv := newTestServer()
+	defer r.DB(testDB).Table(testTable).Get("changes_1").Delete().RunWrite(testSession)
+
+	res, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database: testDB,
+		Table:    testTable,
+		Data:     json.RawMessage(`[{"id": "1", "name": "Duplicate"}, {"id": "changes_1", "name": "New"}]`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res == nil || !res.IsError {
+		t.Error("expected an error result")
+	}
+	if output.Inserted != 1 || output.Errors != 1 || output.FirstError == "" {
+		t.Errorf("expected 1 inserted and 1 error, got %+v", output)
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// maxPreview bounds how many documents a dry run lists in its preview.
+const maxPreview = 10
+
+// WritePreview is one document a dry run would write or delete.
+type WritePreview struct {
+	// Action is insert, replace, unchanged, delete, not_found, conflict or
+	// error.
+	Action string      `json:"action"`
+	Key    interface{} `json:"key,omitempty"`
+	// Old is the stored document that would be replaced or deleted.
+	Old interface{} `json:"old,omitempty"`
+	// New is the document as it would be stored after the write.
+	New   interface{} `json:"new,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// previewWrite reports what write_data would do without writing: the counts
+// RethinkDB would return and a sample of the affected documents. Conflicts
+// are found by looking up the written documents' primary keys, and deletes
+// by counting the documents the keys or filter match.
+func (s *RethinkDBServer) previewWrite(ctx context.Context, db, table, operation string, data interface{}) (WriteDataOutput, error) {
+	output := WriteDataOutput{Database: db, Table: table, Operation: operation, DryRun: true}
+	pk, err := s.primaryKey(ctx, db, table)
+	if err != nil {
+		return WriteDataOutput{}, err
+	}
+
+	if operation == "delete" {
+		return s.previewDelete(ctx, db, table, pk, data, output)
+	}
+
+	docs := documentList(data)
+	if operation == "update" {
+		if _, err := documentKeys(pk, docs); err != nil {
+			return WriteDataOutput{}, fmt.Errorf("invalid update: %w", err)
+		}
+	}
+	stored, err := s.storedDocuments(ctx, db, table, pk, docs)
+	if err != nil {
+		return WriteDataOutput{}, err
+	}
+
+	seen := make(map[interface{}]bool, len(docs))
+	for _, doc := range docs {
+		obj, _ := doc.(map[string]interface{})
+		key, hasKey := obj[pk]
+		hk := hashableKey(key)
+		existing, exists := stored[hk]
+
+		preview := WritePreview{Key: key}
+		switch {
+		case obj == nil:
+			preview.Action = "error"
+			preview.Error = "Expected type OBJECT"
+			output.Errors++
+		case hasKey && seen[hk] && operation == "insert":
+			preview.Action = "error"
+			preview.Error = "Duplicate primary key `" + pk + "` in the written documents"
+			output.Errors++
+		case exists && operation == "insert":
+			preview.Action = "error"
+			preview.Error = "Duplicate primary key `" + pk + "`"
+			preview.Old = existing
+			output.Errors++
+		case !exists && operation == "update":
+			preview.Action = "not_found"
+			output.NotFound = append(output.NotFound, key)
+		case exists:
+			after := obj
+			if operation == "update" {
+				after = mergeDocument(existing, obj)
+			}
+			preview.Old, preview.New = existing, after
+			if sameDocument(existing, after) {
+				preview.Action = "unchanged"
+				output.Unchanged++
+			} else {
+				preview.Action = "replace"
+				output.Replaced++
+			}
+		default:
+			preview.Action = "insert"
+			preview.New = obj
+			output.Inserted++
+		}
+		if hasKey {
+			seen[hk] = true
+		}
+		if preview.Error != "" && output.FirstError == "" {
+			output.FirstError = preview.Error
+		}
+		if len(output.Preview) < maxPreview {
+			output.Preview = append(output.Preview, preview)
+		}
+	}
+	return output, nil
+}
+
+// previewDelete counts and samples the documents a delete would remove,
+// selecting them the same way WriteData does.
+func (s *RethinkDBServer) previewDelete(ctx context.Context, db, table, pk string, data interface{}, output WriteDataOutput) (WriteDataOutput, error) {
+	selection, keys, err := deleteSelection(r.DB(db).Table(table), pk, data)
+	if err != nil {
+		return WriteDataOutput{}, err
+	}
+	if output.NotFound, err = s.missingKeys(ctx, db, table, keys); err != nil {
+		return WriteDataOutput{}, err
+	}
+
+	cursor, err := r.Expr(map[string]interface{}{
+		"count":  selection.Count(),
+		"sample": selection.Limit(maxPreview).CoerceTo("array"),
+	}).Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return WriteDataOutput{}, fmt.Errorf("failed to preview delete: %w", err)
+	}
+	defer cursor.Close()
+	var result struct {
+		Count  int                      `rethinkdb:"count"`
+		Sample []map[string]interface{} `rethinkdb:"sample"`
+	}
+	if err := cursor.One(&result); err != nil {
+		return WriteDataOutput{}, fmt.Errorf("failed to preview delete: %w", err)
+	}
+
+	output.Deleted = result.Count
+	for _, doc := range result.Sample {
+		output.Preview = append(output.Preview, WritePreview{Action: "delete", Key: doc[pk], Old: doc})
+	}
+	return output, nil
+}
+
+// previewUpdate counts and samples the documents an update by keys or
+// filter would change. updated computes a document after the update.
+func (s *RethinkDBServer) previewUpdate(ctx context.Context, selection r.Term, updated func(r.Term) interface{}, output *WriteDataOutput) error {
+	pk, err := s.primaryKey(ctx, output.Database, output.Table)
+	if err != nil {
+		return err
+	}
+	cursor, err := r.Expr(map[string]interface{}{
+		"count": selection.Count(),
+		"unchanged": selection.Filter(func(row r.Term) interface{} {
+			return r.Expr(updated(row)).Eq(row)
+		}).Count(),
+		"sample": selection.Limit(maxPreview).Map(func(row r.Term) interface{} {
+			return map[string]interface{}{"old": row, "new": updated(row)}
+		}).CoerceTo("array"),
+	}).Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return fmt.Errorf("failed to preview update: %w", err)
+	}
+	defer cursor.Close()
+	var result struct {
+		Count     int `rethinkdb:"count"`
+		Unchanged int `rethinkdb:"unchanged"`
+		Sample    []struct {
+			Old map[string]interface{} `rethinkdb:"old"`
+			New map[string]interface{} `rethinkdb:"new"`
+		} `rethinkdb:"sample"`
+	}
+	if err := cursor.One(&result); err != nil {
+		return fmt.Errorf("failed to preview update: %w", err)
+	}
+
+	output.Replaced = result.Count - result.Unchanged
+	output.Unchanged = result.Unchanged
+	for _, change := range result.Sample {
+		action := "replace"
+		if sameDocument(change.Old, change.New) {
+			action = "unchanged"
+		}
+		output.Preview = append(output.Preview, WritePreview{Action: action, Key: change.Old[pk], Old: change.Old, New: change.New})
+	}
+	return nil
+}
+
+// sameDocument reports whether two documents hold the same JSON value, as
+// RethinkDB decides whether a write left a document unchanged.
+func sameDocument(a, b map[string]interface{}) bool {
+	return sameValue(a, b)
+}
+
+// sameValue reports whether two values have the same JSON form, so that
+// numbers decoded as different Go types still compare equal.
+func sameValue(a, b interface{}) bool {
+	var na, nb interface{}
+	da, errA := json.Marshal(a)
+	db, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	if json.Unmarshal(da, &na) != nil || json.Unmarshal(db, &nb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(na, nb)
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── write_data dry runs ────────────────────────────────────────────────────
+
+func TestSameDocument(t *testing.T) {
+	a := map[string]interface{}{"id": "1", "age": 30.0, "tags": []interface{}{"a"}}
+	b := map[string]interface{}{"id": "1", "age": 30, "tags": []string{"a"}}
+	if !sameDocument(a, b) {
+		t.Error("expected documents with the same JSON value to be the same")
+	}
+	if sameDocument(a, map[string]interface{}{"id": "1", "age": 31.0, "tags": []interface{}{"a"}}) {
+		t.Error("expected different documents to differ")
+	}
+}
+
+func countDocs(t *testing.T, table string) int {
+	t.Helper()
+	cursor, err := r.DB(testDB).Table(table).Count().Run(testSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cursor.Close()
+	var n int
+	cursor.One(&n)
+	return n
+}
+
+func TestWriteData_DryRunInsert(t *testing.T) {
+	srv := newTestServer()
+	before := countDocs(t, testTable)
+
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database: testDB,
+		Table:    testTable,
+		Data:     json.RawMessage(`[{"id": "1", "name": "Duplicate"}, {"id": "dry_1", "name": "New"}, {"id": "dry_1", "name": "Again"}]`),
+		DryRun:   true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !output.DryRun || output.Inserted != 1 || output.Errors != 2 {
+		t.Errorf("expected 1 insert and 2 errors, got %+v", output)
+	}
+	if len(output.Preview) != 3 || output.Preview[0].Action != "error" || output.Preview[1].Action != "insert" {
+		t.Errorf("unexpected preview: %+v", output.Preview)
+	}
+	if after := countDocs(t, testTable); after != before {
+		t.Errorf("expected nothing written, count went from %d to %d", before, after)
+	}
+}
+
+func TestWriteData_DryRunUpdate(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Data:      json.RawMessage(`[{"id": "1", "age": 31}, {"id": "2", "age": 25}]`),
+		Operation: "update",
+		DryRun:    true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Replaced != 1 || output.Unchanged != 1 {
+		t.Errorf("expected 1 replaced and 1 unchanged, got %+v", output)
+	}
+	replaced := output.Preview[0].New.(map[string]interface{})
+	if replaced["age"] != 31.0 || replaced["name"] != "Alice" {
+		t.Errorf("expected the merged document, got %v", replaced)
+	}
+
+	cursor, _ := r.DB(testDB).Table(testTable).Get("1").Field("age").Run(testSession)
+	defer cursor.Close()
+	var age int
+	cursor.One(&age)
+	if age != 30 {
+		t.Errorf("expected Alice to be unchanged, got age %d", age)
+	}
+}
+
+func TestWriteData_DryRunDeleteByFilter(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Data:      json.RawMessage(`{"status": "active"}`),
+		Operation: "delete",
+		DryRun:    true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Deleted != 2 || len(output.Preview) != 2 || output.Preview[0].Action != "delete" {
+		t.Errorf("expected 2 deletes previewed, got %+v", output)
+	}
+	if n := countDocs(t, testTable); n < 3 {
+		t.Errorf("expected nothing deleted, %d documents left", n)
+	}
+}
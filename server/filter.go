@@ -0,0 +1,280 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// predicate builds a boolean ReQL term for a single document.
+type predicate func(row r.Term) r.Term
+
+// compileFilter compiles a Mongo-style filter document into a ReQL predicate
+// suitable for Filter. It returns nil for an empty filter.
+//
+// Plain values match by equality and nested objects match field by field, so
+// {"status": "active"} and {"address": {"city": "Paris"}} behave like the
+// object form of Filter. Keys may use dotted paths ("address.city"). Field
+// operators are $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $regex (with
+// optional $options: "i"), $exists and $not; documents combine with $and,
+// $or and $not.
+//
+// A predicate that fails on a document, for example comparing a missing
+// field, is false for that document rather than aborting the query.
+func compileFilter(filter map[string]interface{}) (func(r.Term) r.Term, error) {
+	if len(filter) == 0 {
+		return nil, nil
+	}
+	pred, err := compileDocument(filter, nil)
+	if err != nil {
+		return nil, err
+	}
+	return pred, nil
+}
+
+func compileDocument(doc map[string]interface{}, prefix []string) (predicate, error) {
+	preds := make([]predicate, 0, len(doc))
+	for _, key := range sortedKeys(doc) {
+		value := doc[key]
+		switch key {
+		case "$and", "$or":
+			items, ok := value.([]interface{})
+			if !ok || len(items) == 0 {
+				return nil, fmt.Errorf("%s requires a non-empty array of filter objects", key)
+			}
+			subs := make([]predicate, 0, len(items))
+			for _, item := range items {
+				sub, ok := item.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("%s requires a non-empty array of filter objects", key)
+				}
+				pred, err := compileDocument(sub, prefix)
+				if err != nil {
+					return nil, err
+				}
+				subs = append(subs, pred)
+			}
+			if key == "$and" {
+				preds = append(preds, allOf(subs))
+			} else {
+				preds = append(preds, anyOf(subs))
+			}
+		case "$not":
+			sub, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("$not requires a filter object")
+			}
+			pred, err := compileDocument(sub, prefix)
+			if err != nil {
+				return nil, err
+			}
+			preds = append(preds, negate(pred))
+		default:
+			if strings.HasPrefix(key, "$") {
+				return nil, fmt.Errorf("unknown filter operator %q", key)
+			}
+			path, err := fieldPath(prefix, key)
+			if err != nil {
+				return nil, err
+			}
+			pred, err := compileField(path, value)
+			if err != nil {
+				return nil, err
+			}
+			preds = append(preds, pred)
+		}
+	}
+	return allOf(preds), nil
+}
+
+func compileField(path []string, value interface{}) (predicate, error) {
+	ops, ok := value.(map[string]interface{})
+	if !ok || len(ops) == 0 {
+		return compareField(path, "$eq", value)
+	}
+
+	isOperator := 0
+	for key := range ops {
+		if strings.HasPrefix(key, "$") {
+			isOperator++
+		}
+	}
+	if isOperator == 0 {
+		// A nested object matches each of its fields, like Filter(object).
+		return compileDocument(ops, path)
+	}
+	if isOperator != len(ops) {
+		return nil, fmt.Errorf("field %q mixes operators and plain fields", strings.Join(path, "."))
+	}
+
+	preds := make([]predicate, 0, len(ops))
+	for _, op := range sortedKeys(ops) {
+		arg := ops[op]
+		switch op {
+		case "$eq", "$ne", "$gt", "$gte", "$lt", "$lte", "$in", "$nin":
+			pred, err := compareField(path, op, arg)
+			if err != nil {
+				return nil, err
+			}
+			preds = append(preds, pred)
+		case "$regex":
+			pattern, ok := arg.(string)
+			if !ok {
+				return nil, fmt.Errorf("$regex on %q requires a string pattern", strings.Join(path, "."))
+			}
+			if options, ok := ops["$options"].(string); ok && strings.Contains(options, "i") {
+				pattern = "(?i)" + pattern
+			}
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("invalid $regex on %q: %w", strings.Join(path, "."), err)
+			}
+			preds = append(preds, leaf(func(row r.Term) r.Term {
+				return fieldTerm(row, path).Match(pattern).Ne(nil)
+			}))
+		case "$options":
+			if _, ok := ops["$regex"]; !ok {
+				return nil, fmt.Errorf("$options on %q requires $regex", strings.Join(path, "."))
+			}
+		case "$exists":
+			exists, ok := arg.(bool)
+			if !ok {
+				return nil, fmt.Errorf("$exists on %q requires true or false", strings.Join(path, "."))
+			}
+			pred := leaf(func(row r.Term) r.Term {
+				return row.HasFields(hasFieldsSelector(path))
+			})
+			if !exists {
+				pred = negate(pred)
+			}
+			preds = append(preds, pred)
+		case "$not":
+			sub, ok := arg.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("$not on %q requires an operator object", strings.Join(path, "."))
+			}
+			pred, err := compileField(path, sub)
+			if err != nil {
+				return nil, err
+			}
+			preds = append(preds, negate(pred))
+		default:
+			return nil, fmt.Errorf("unknown filter operator %q", op)
+		}
+	}
+	return allOf(preds), nil
+}
+
+func compareField(path []string, op string, arg interface{}) (predicate, error) {
+	switch op {
+	case "$eq":
+		// Missing fields compare as null, so {"field": null} matches them.
+		return leaf(func(row r.Term) r.Term { return fieldTerm(row, path).Default(nil).Eq(arg) }), nil
+	case "$ne":
+		return leaf(func(row r.Term) r.Term { return fieldTerm(row, path).Default(nil).Ne(arg) }), nil
+	case "$gt":
+		return leaf(func(row r.Term) r.Term { return fieldTerm(row, path).Gt(arg) }), nil
+	case "$gte":
+		return leaf(func(row r.Term) r.Term { return fieldTerm(row, path).Ge(arg) }), nil
+	case "$lt":
+		return leaf(func(row r.Term) r.Term { return fieldTerm(row, path).Lt(arg) }), nil
+	case "$lte":
+		return leaf(func(row r.Term) r.Term { return fieldTerm(row, path).Le(arg) }), nil
+	case "$in", "$nin":
+		values, ok := arg.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s on %q requires an array", op, strings.Join(path, "."))
+		}
+		pred := leaf(func(row r.Term) r.Term {
+			return r.Expr(values).Contains(fieldTerm(row, path).Default(nil))
+		})
+		if op == "$nin" {
+			pred = negate(pred)
+		}
+		return pred, nil
+	}
+	return nil, fmt.Errorf("unknown filter operator %q", op)
+}
+
+// fieldPath splits a possibly dotted key and appends it to prefix.
+func fieldPath(prefix []string, key string) ([]string, error) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("invalid field path %q", key)
+		}
+	}
+	path := make([]string, 0, len(prefix)+len(parts))
+	path = append(path, prefix...)
+	return append(path, parts...), nil
+}
+
+// fieldTerm selects the nested field at path from row.
+func fieldTerm(row r.Term, path []string) r.Term {
+	term := row
+	for _, part := range path {
+		term = term.Field(part)
+	}
+	return term
+}
+
+// hasFieldsSelector converts a path into the nested selector HasFields uses,
+// e.g. ["address", "city"] becomes {"address": {"city": true}}.
+func hasFieldsSelector(path []string) interface{} {
+	var selector interface{} = true
+	for i := len(path) - 1; i >= 0; i-- {
+		selector = map[string]interface{}{path[i]: selector}
+	}
+	return selector
+}
+
+// leaf makes a predicate that evaluates to false instead of erroring, so that
+// negation and $or behave sensibly on documents missing the field.
+func leaf(pred predicate) predicate {
+	return func(row r.Term) r.Term {
+		return pred(row).Default(false)
+	}
+}
+
+func negate(pred predicate) predicate {
+	return func(row r.Term) r.Term {
+		return pred(row).Not()
+	}
+}
+
+func allOf(preds []predicate) predicate {
+	if len(preds) == 1 {
+		return preds[0]
+	}
+	return func(row r.Term) r.Term {
+		terms := make([]interface{}, len(preds))
+		for i, pred := range preds {
+			terms[i] = pred(row)
+		}
+		return r.And(terms...)
+	}
+}
+
+func anyOf(preds []predicate) predicate {
+	if len(preds) == 1 {
+		return preds[0]
+	}
+	return func(row r.Term) r.Term {
+		terms := make([]interface{}, len(preds))
+		for i, pred := range preds {
+			terms[i] = pred(row)
+		}
+		return r.Or(terms...)
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── filter DSL ─────────────────────────────────────────────────────────────
+
+func TestCompileFilter_InvalidFilters_ReturnError(t *testing.T) {
+	filters := []string{
+		`{"age": {"$foo": 1}}`,
+		`{"$nor": []}`,
+		`{"$or": {"age": 1}}`,
+		`{"age": {"$in": 3}}`,
+		`{"name": {"$regex": "("}}`,
+		`{"name": {"$options": "i"}}`,
+		`{"age": {"$gt": 1, "plain": 2}}`,
+		`{"age": {"$exists": "yes"}}`,
+		`{"address..city": 1}`,
+	}
+	for _, f := range filters {
+		var filter map[string]interface{}
+		if err := json.Unmarshal([]byte(f), &filter); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := compileFilter(filter); err == nil {
+			t.Errorf("expected error compiling %s", f)
+		}
+	}
+}
+
+func TestCompileFilter_Empty_ReturnsNil(t *testing.T) {
+	pred, err := compileFilter(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pred != nil {
+		t.Error("expected nil predicate for empty filter")
+	}
+}
+
+func queryCount(t *testing.T, filter string) int {
+	t.Helper()
+	var f map[string]interface{}
+	if err := json.Unmarshal([]byte(filter), &f); err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer()
+	_, output, err := srv.QueryTable(context.Background(), &mcp.CallToolRequest{}, QueryTableInput{
+		Database: testDB,
+		Table:    testTable,
+		Filter:   f,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error for %s: %v", filter, err)
+	}
+	return output.Count
+}
+
+func TestQueryTable_FilterOperators(t *testing.T) {
+	cases := []struct {
+		filter string
+		want   int
+	}{
+		{`{"age": {"$gt": 28}}`, 2},
+		{`{"age": {"$gte": 30, "$lt": 35}}`, 1},
+		{`{"status": {"$ne": "active"}}`, 1},
+		{`{"name": {"$in": ["Alice", "Bob"]}}`, 2},
+		{`{"name": {"$nin": ["Alice", "Bob"]}}`, 1},
+		{`{"name": {"$regex": "^a", "$options": "i"}}`, 1},
+		{`{"nickname": {"$exists": false}}`, 3},
+		{`{"$or": [{"age": 25}, {"name": "Charlie"}]}`, 2},
+		{`{"$and": [{"status": "active"}, {"age": {"$lt": 33}}]}`, 1},
+		{`{"$not": {"status": "active"}}`, 1},
+		{`{"age": {"$not": {"$gt": 28}}}`, 1},
+	}
+	for _, c := range cases {
+		if got := queryCount(t, c.filter); got != c.want {
+			t.Errorf("filter %s: expected %d results, got %d", c.filter, c.want, got)
+		}
+	}
+}
+
+func TestQueryTable_FilterNestedPath(t *testing.T) {
+	r.DB(testDB).Table(testTable).Insert(map[string]interface{}{
+		"id": "nested_filter", "name": "Eve", "address": map[string]interface{}{"city": "Paris"},
+	}).RunWrite(testSession)
+	defer r.DB(testDB).Table(testTable).Get("nested_filter").Delete().RunWrite(testSession)
+
+	if got := queryCount(t, `{"address.city": "Paris"}`); got != 1 {
+		t.Errorf("expected 1 result for dotted path, got %d", got)
+	}
+	if got := queryCount(t, `{"address": {"city": "Paris"}}`); got != 1 {
+		t.Errorf("expected 1 result for nested object, got %d", got)
+	}
+	if got := queryCount(t, `{"address.city": {"$exists": true}}`); got != 1 {
+		t.Errorf("expected 1 result for nested $exists, got %d", got)
+	}
+}
+
+func TestAggregate_CountWithOperatorFilter(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.Aggregate(context.Background(), &mcp.CallToolRequest{}, AggregateInput{
+		Database:  testDB,
+		Table:     testTable,
+		Operation: "count",
+		Filter:    map[string]interface{}{"age": map[string]interface{}{"$lt": 31}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count, ok := output.Value.(float64); !ok || count != 2 {
+		t.Errorf("expected count 2, got %v", output.Value)
+	}
+}
+
+func TestWriteData_DeleteByOperatorFilter(t *testing.T) {
+	srv := newTestServer()
+	r.DB(testDB).Table(testTable).Insert([]map[string]interface{}{
+		{"id": "op_del_1", "batch": "op_del", "score": 1},
+		{"id": "op_del_2", "batch": "op_del", "score": 5},
+	}).RunWrite(testSession)
+	defer r.DB(testDB).Table(testTable).Filter(map[string]interface{}{"batch": "op_del"}).Delete().RunWrite(testSession)
+
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Operation: "delete",
+		Data:      json.RawMessage(`{"batch": "op_del", "score": {"$gt": 2}}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Deleted != 1 {
+		t.Errorf("expected 1 deleted, got %d", output.Deleted)
+	}
+}
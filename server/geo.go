@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/types"
+)
+
+// geoUnits are the distance units RethinkDB accepts.
+var geoUnits = map[string]bool{"m": true, "km": true, "mi": true, "nm": true, "ft": true}
+
+// ─── Input/Output structs ────────────────────────────────────────────────────
+
+// CircleInput describes a circle approximated by a polygon, as built by
+// r.circle.
+type CircleInput struct {
+	Center      []float64 `json:"center" jsonschema:"Center as [longitude, latitude]"`
+	Radius      float64   `json:"radius" jsonschema:"Radius of the circle"`
+	Unit        string    `json:"unit,omitempty" jsonschema:"Unit of the radius: m, km, mi, nm or ft (default m)"`
+	NumVertices int       `json:"num_vertices,omitempty" jsonschema:"Number of vertices of the polygon approximating the circle (default 32)"`
+}
+
+type GeoQueryInput struct {
+	Database   string                 `json:"database" jsonschema:"The database name"`
+	Table      string                 `json:"table" jsonschema:"The table name"`
+	Operation  string                 `json:"operation" jsonschema:"Operation: get_intersecting, get_nearest, or includes"`
+	Index      string                 `json:"index,omitempty" jsonschema:"Geospatial index to use (required for get_intersecting and get_nearest, optional for includes)"`
+	Field      string                 `json:"field,omitempty" jsonschema:"Document field holding the geometry (for includes; defaults to index)"`
+	Geometry   map[string]interface{} `json:"geometry,omitempty" jsonschema:"GeoJSON geometry: Point, LineString or Polygon. get_nearest requires a Point"`
+	Circle     *CircleInput           `json:"circle,omitempty" jsonschema:"Circle to use instead of geometry (for get_intersecting and includes)"`
+	MaxDist    float64                `json:"max_dist,omitempty" jsonschema:"Maximum distance from the point (for get_nearest, default 100000 m)"`
+	Unit       string                 `json:"unit,omitempty" jsonschema:"Unit of max_dist and of the returned distances: m, km, mi, nm or ft (for get_nearest, default m)"`
+	MaxResults int                    `json:"max_results,omitempty" jsonschema:"Maximum number of results (for get_nearest, default 100, max 1000)"`
+	Limit      int                    `json:"limit,omitempty" jsonschema:"Maximum number of results (default 100, max 1000)"`
+	TimeoutMs  int                    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+type GeoQueryOutput struct {
+	Database  string `json:"database"`
+	Table     string `json:"table"`
+	Operation string `json:"operation"`
+	Count     int    `json:"count"`
+	// Results are documents for get_intersecting and includes, and
+	// {"dist": ..., "doc": ...} pairs ordered by distance for get_nearest.
+	// Geometry values are returned as plain GeoJSON.
+	Results []any `json:"results"`
+}
+
+// ─── Handler ─────────────────────────────────────────────────────────────────
+
+func (s *RethinkDBServer) GeoQuery(ctx context.Context, req *mcp.CallToolRequest, input GeoQueryInput) (*mcp.CallToolResult, GeoQueryOutput, error) {
+	if input.Database == "" || input.Table == "" {
+		return nil, GeoQueryOutput{}, fmt.Errorf("database and table names are required")
+	}
+
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, GeoQueryOutput{}, err
+	}
+
+	geometry, err := geometryTerm(input.Geometry, input.Circle)
+	if err != nil {
+		return nil, GeoQueryOutput{}, err
+	}
+
+	table := r.DB(input.Database).Table(input.Table)
+	limit := applyLimit(input.Limit)
+
+	var query r.Term
+	switch input.Operation {
+	case "get_intersecting":
+		if input.Index == "" {
+			return nil, GeoQueryOutput{}, fmt.Errorf("index is required for get_intersecting")
+		}
+		query = table.GetIntersecting(geometry, r.GetIntersectingOpts{Index: input.Index}).Limit(limit)
+
+	case "get_nearest":
+		if input.Index == "" {
+			return nil, GeoQueryOutput{}, fmt.Errorf("index is required for get_nearest")
+		}
+		if input.Circle != nil || input.Geometry["type"] != "Point" {
+			return nil, GeoQueryOutput{}, fmt.Errorf("get_nearest requires a Point geometry")
+		}
+		opts := r.GetNearestOpts{
+			Index:      input.Index,
+			MaxResults: applyLimit(input.MaxResults),
+		}
+		if input.MaxDist < 0 {
+			return nil, GeoQueryOutput{}, fmt.Errorf("max_dist must be positive")
+		}
+		if input.MaxDist > 0 {
+			opts.MaxDist = input.MaxDist
+		}
+		if input.Unit != "" {
+			if !geoUnits[input.Unit] {
+				return nil, GeoQueryOutput{}, fmt.Errorf("invalid unit %q: must be one of m, km, mi, nm, ft", input.Unit)
+			}
+			opts.Unit = input.Unit
+		}
+		query = table.GetNearest(geometry, opts)
+
+	case "includes":
+		if input.Circle == nil && input.Geometry["type"] != "Polygon" {
+			return nil, GeoQueryOutput{}, fmt.Errorf("includes requires a Polygon geometry or a circle")
+		}
+		field := input.Field
+		if field == "" {
+			field = input.Index
+		}
+		if field == "" {
+			return nil, GeoQueryOutput{}, fmt.Errorf("field or index is required for includes")
+		}
+		// Narrow the candidates with the index first, when there is one;
+		// includes itself cannot use an index.
+		query = table
+		if input.Index != "" {
+			query = table.GetIntersecting(geometry, r.GetIntersectingOpts{Index: input.Index})
+		}
+		query = query.Filter(func(row r.Term) r.Term {
+			return geometry.Includes(row.Field(field)).Default(false)
+		}).Limit(limit)
+
+	default:
+		return nil, GeoQueryOutput{}, fmt.Errorf("invalid operation %q: must be one of get_intersecting, get_nearest, includes", input.Operation)
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "geo_query", input.TimeoutMs)
+	defer cancel()
+	what := describeQuery(fmt.Sprintf("geo_query on %s.%s", input.Database, input.Table), query)
+
+	cursor, err := query.Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, GeoQueryOutput{}, queryError(ctx, what, fmt.Errorf("failed to execute %s: %w", input.Operation, err))
+	}
+	defer cursor.Close()
+
+	var results []interface{}
+	if err := cursor.All(&results); err != nil {
+		return nil, GeoQueryOutput{}, queryError(ctx, what, fmt.Errorf("failed to read %s results: %w", input.Operation, err))
+	}
+	for i, result := range results {
+		results[i] = toGeoJSON(result)
+	}
+	if results == nil {
+		results = []interface{}{}
+	}
+
+	return nil, GeoQueryOutput{
+		Database:  input.Database,
+		Table:     input.Table,
+		Operation: input.Operation,
+		Count:     len(results),
+		Results:   results,
+	}, nil
+}
+
+// geometryTerm builds the ReQL geometry for a GeoJSON object or a circle;
+// exactly one of them must be given.
+func geometryTerm(geometry map[string]interface{}, circle *CircleInput) (r.Term, error) {
+	switch {
+	case geometry != nil && circle != nil:
+		return r.Term{}, fmt.Errorf("geometry and circle are mutually exclusive")
+	case circle != nil:
+		if len(circle.Center) != 2 {
+			return r.Term{}, fmt.Errorf("circle center must be [longitude, latitude]")
+		}
+		if circle.Radius <= 0 {
+			return r.Term{}, fmt.Errorf("circle radius must be positive")
+		}
+		opts := r.CircleOpts{}
+		if circle.Unit != "" {
+			if !geoUnits[circle.Unit] {
+				return r.Term{}, fmt.Errorf("invalid unit %q: must be one of m, km, mi, nm, ft", circle.Unit)
+			}
+			opts.Unit = circle.Unit
+		}
+		if circle.NumVertices > 0 {
+			opts.NumVertices = circle.NumVertices
+		}
+		return r.Circle(r.Point(circle.Center[0], circle.Center[1]), circle.Radius, opts), nil
+	case geometry != nil:
+		switch geometry["type"] {
+		case "Point", "LineString", "Polygon":
+		default:
+			return r.Term{}, fmt.Errorf("geometry type must be Point, LineString or Polygon, got %v", geometry["type"])
+		}
+		if _, ok := geometry["coordinates"].([]interface{}); !ok {
+			return r.Term{}, fmt.Errorf("geometry requires a coordinates array")
+		}
+		return r.GeoJSON(geometry), nil
+	}
+	return r.Term{}, fmt.Errorf("geometry or circle is required")
+}
+
+// toGeoJSON replaces the driver's native geometry values anywhere in v with
+// plain GeoJSON objects.
+func toGeoJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case types.Geometry:
+		var coords interface{}
+		switch v.Type {
+		case "Point":
+			coords = v.Point.Coords()
+		case "LineString":
+			coords = v.Line.Coords()
+		case "Polygon":
+			coords = v.Lines.Coords()
+		}
+		return map[string]interface{}{"type": v.Type, "coordinates": coords}
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = toGeoJSON(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = toGeoJSON(item)
+		}
+	}
+	return v
+}
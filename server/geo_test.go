@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/types"
+)
+
+// ─── geo_query ──────────────────────────────────────────────────────────────
+
+const testGeoTable = "mcp_test_geo_table"
+
+// setupGeoTable creates a table of places with a geo index on location.
+func setupGeoTable(t *testing.T) {
+	t.Helper()
+	r.DB(testDB).TableCreate(testGeoTable).RunWrite(testSession)
+	r.DB(testDB).Table(testGeoTable).Delete().RunWrite(testSession)
+	r.DB(testDB).Table(testGeoTable).IndexCreate("location", r.IndexCreateOpts{Geo: true}).RunWrite(testSession)
+	r.DB(testDB).Table(testGeoTable).IndexWait().Run(testSession)
+
+	places := []map[string]interface{}{
+		{"id": "paris", "location": r.Point(2.3522, 48.8566)},
+		{"id": "versailles", "location": r.Point(2.1301, 48.8049)},
+		{"id": "london", "location": r.Point(-0.1276, 51.5072)},
+	}
+	for _, place := range places {
+		if _, err := r.DB(testDB).Table(testGeoTable).Insert(place).RunWrite(testSession); err != nil {
+			t.Fatalf("failed to seed geo table: %v", err)
+		}
+	}
+}
+
+func resultIDs(results []any) map[string]bool {
+	ids := map[string]bool{}
+	for _, result := range results {
+		if doc, ok := result.(map[string]interface{}); ok {
+			if id, ok := doc["id"].(string); ok {
+				ids[id] = true
+			}
+		}
+	}
+	return ids
+}
+
+func TestGeometryTerm_Validation(t *testing.T) {
+	cases := []struct {
+		name     string
+		geometry map[string]interface{}
+		circle   *CircleInput
+	}{
+		{"missing", nil, nil},
+		{"both", map[string]interface{}{"type": "Point", "coordinates": []interface{}{0.0, 0.0}}, &CircleInput{Center: []float64{0, 0}, Radius: 1}},
+		{"unknown type", map[string]interface{}{"type": "MultiPoint", "coordinates": []interface{}{}}, nil},
+		{"no coordinates", map[string]interface{}{"type": "Point"}, nil},
+		{"bad center", nil, &CircleInput{Center: []float64{0}, Radius: 1}},
+		{"bad radius", nil, &CircleInput{Center: []float64{0, 0}}},
+		{"bad unit", nil, &CircleInput{Center: []float64{0, 0}, Radius: 1, Unit: "parsec"}},
+	}
+	for _, tc := range cases {
+		if _, err := geometryTerm(tc.geometry, tc.circle); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
+
+func TestToGeoJSON(t *testing.T) {
+	doc := map[string]interface{}{
+		"id": "paris",
+		"location": types.Geometry{
+			Type:  "Point",
+			Point: types.Point{Lon: 2.35, Lat: 48.85},
+		},
+	}
+	converted := toGeoJSON(doc).(map[string]interface{})
+	location, ok := converted["location"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a GeoJSON object, got %T", converted["location"])
+	}
+	if location["type"] != "Point" {
+		t.Errorf("expected type Point, got %v", location["type"])
+	}
+	coords, ok := location["coordinates"].([]interface{})
+	if !ok || len(coords) != 2 || coords[0] != 2.35 || coords[1] != 48.85 {
+		t.Errorf("expected coordinates [2.35 48.85], got %v", location["coordinates"])
+	}
+}
+
+func TestGeoQuery_GetIntersecting(t *testing.T) {
+	setupGeoTable(t)
+	srv := newTestServer()
+	_, output, err := srv.GeoQuery(context.Background(), &mcp.CallToolRequest{}, GeoQueryInput{
+		Database:  testDB,
+		Table:     testGeoTable,
+		Operation: "get_intersecting",
+		Index:     "location",
+		Circle:    &CircleInput{Center: []float64{2.3522, 48.8566}, Radius: 50, Unit: "km"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ids := resultIDs(output.Results)
+	if len(ids) != 2 || !ids["paris"] || !ids["versailles"] {
+		t.Errorf("expected paris and versailles, got %v", ids)
+	}
+	doc := output.Results[0].(map[string]interface{})
+	if location, ok := doc["location"].(map[string]interface{}); !ok || location["type"] != "Point" {
+		t.Errorf("expected location as GeoJSON, got %v", doc["location"])
+	}
+}
+
+func TestGeoQuery_GetNearest(t *testing.T) {
+	setupGeoTable(t)
+	srv := newTestServer()
+	_, output, err := srv.GeoQuery(context.Background(), &mcp.CallToolRequest{}, GeoQueryInput{
+		Database:   testDB,
+		Table:      testGeoTable,
+		Operation:  "get_nearest",
+		Index:      "location",
+		Geometry:   map[string]interface{}{"type": "Point", "coordinates": []interface{}{2.3, 48.85}},
+		MaxDist:    100,
+		Unit:       "km",
+		MaxResults: 5,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Count != 2 {
+		t.Fatalf("expected 2 results within 100 km, got %d", output.Count)
+	}
+	first := output.Results[0].(map[string]interface{})
+	if doc := first["doc"].(map[string]interface{}); doc["id"] != "paris" {
+		t.Errorf("expected paris to be nearest, got %v", doc["id"])
+	}
+	if _, ok := first["dist"].(float64); !ok {
+		t.Errorf("expected a numeric dist, got %v", first["dist"])
+	}
+}
+
+func TestGeoQuery_GetNearestRequiresPoint(t *testing.T) {
+	srv := newTestServer()
+	_, _, err := srv.GeoQuery(context.Background(), &mcp.CallToolRequest{}, GeoQueryInput{
+		Database:  testDB,
+		Table:     testGeoTable,
+		Operation: "get_nearest",
+		Index:     "location",
+		Circle:    &CircleInput{Center: []float64{0, 0}, Radius: 1},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a non-point geometry")
+	}
+}
+
+func TestGeoQuery_Includes(t *testing.T) {
+	setupGeoTable(t)
+	srv := newTestServer()
+	polygon := map[string]interface{}{
+		"type": "Polygon",
+		"coordinates": []interface{}{[]interface{}{
+			[]interface{}{-1.0, 51.0},
+			[]interface{}{1.0, 51.0},
+			[]interface{}{1.0, 52.0},
+			[]interface{}{-1.0, 52.0},
+			[]interface{}{-1.0, 51.0},
+		}},
+	}
+	_, output, err := srv.GeoQuery(context.Background(), &mcp.CallToolRequest{}, GeoQueryInput{
+		Database:  testDB,
+		Table:     testGeoTable,
+		Operation: "includes",
+		Index:     "location",
+		Geometry:  polygon,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ids := resultIDs(output.Results)
+	if len(ids) != 1 || !ids["london"] {
+		t.Errorf("expected only london, got %v", ids)
+	}
+}
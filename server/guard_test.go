@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── write_data max affected documents ──────────────────────────────────────
+
+func insertGuardDocs(t *testing.T) {
+	t.Helper()
+	_, err := r.DB(testDB).Table(testTable).Insert([]map[string]interface{}{
+		{"id": "guard_1", "group": "guard"},
+		{"id": "guard_2", "group": "guard"},
+		{"id": "guard_3", "group": "guard"},
+	}).RunWrite(testSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteData_DeleteOverLimitNeedsConfirmation(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithMaxAffected(2))
+	insertGuardDocs(t)
+	defer r.DB(testDB).Table(testTable).GetAll("guard_1", "guard_2", "guard_3").Delete().RunWrite(testSession)
+
+	input := WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Data:      json.RawMessage(`{"group": "guard"}`),
+		Operation: "delete",
+	}
+	res, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res == nil || !res.IsError || output.Matched != 3 || output.Deleted != 0 {
+		t.Fatalf("expected the delete to be refused with 3 matched, got %+v", output)
+	}
+	if !strings.Contains(output.FirstError, "confirm_count") {
+		t.Errorf("expected the refusal to mention confirm_count, got %q", output.FirstError)
+	}
+
+	input.ConfirmCount = 2
+	res, output, err = srv.WriteData(context.Background(), &mcp.CallToolRequest{}, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res == nil || !res.IsError || output.Deleted != 0 {
+		t.Fatalf("expected a wrong confirm_count to be refused, got %+v", output)
+	}
+
+	input.ConfirmCount = 3
+	_, output, err = srv.WriteData(context.Background(), &mcp.CallToolRequest{}, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Deleted != 3 {
+		t.Errorf("expected 3 deleted once confirmed, got %+v", output)
+	}
+}
+
+func TestWriteData_FilteredUpdateOverLimit(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithMaxAffected(2))
+	insertGuardDocs(t)
+	defer r.DB(testDB).Table(testTable).GetAll("guard_1", "guard_2", "guard_3").Delete().RunWrite(testSession)
+
+	res, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Operation: "update",
+		Filter:    map[string]any{"group": "guard"},
+		Data:      json.RawMessage(`{"$set": {"flag": true}}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res == nil || !res.IsError || output.Matched != 3 || output.Replaced != 0 {
+		t.Errorf("expected the update to be refused with 3 matched, got %+v", output)
+	}
+}
+
+func TestWriteData_DeleteUnderLimit(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithMaxAffected(2))
+	insertGuardDocs(t)
+	defer r.DB(testDB).Table(testTable).GetAll("guard_1", "guard_2", "guard_3").Delete().RunWrite(testSession)
+
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Data:      json.RawMessage(`["guard_1", "guard_2"]`),
+		Operation: "delete",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Deleted != 2 {
+		t.Errorf("expected 2 deleted, got %+v", output)
+	}
+}
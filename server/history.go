@@ -0,0 +1,345 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// maxQueryPatterns bounds how many distinct query patterns the history
+// keeps; the least recently seen pattern is evicted first.
+const maxQueryPatterns = 500
+
+// QueryPattern is the index-relevant shape of recorded calls: which fields
+// they compare for equality, which by range, and how they are ordered.
+type QueryPattern struct {
+	Tool           string    `json:"tool"`
+	EqualityFields []string  `json:"equality_fields,omitempty"`
+	RangeFields    []string  `json:"range_fields,omitempty"`
+	OrderBy        string    `json:"order_by,omitempty"`
+	Count          int       `json:"count"`
+	LastSeen       time.Time `json:"last_seen"`
+}
+
+func (p QueryPattern) key() string {
+	return strings.Join([]string{p.Tool, strings.Join(p.EqualityFields, ","), strings.Join(p.RangeFields, ","), p.OrderBy}, "|")
+}
+
+// queryHistory records the patterns of query_table and aggregate calls per
+// table. The zero value is ready to use.
+type queryHistory struct {
+	mu       sync.Mutex
+	patterns map[string]map[string]*QueryPattern // "db.table" -> pattern key -> pattern
+	total    int
+}
+
+// record adds one call with the given filter and order_by to the history.
+// Calls that neither filter nor order are not recorded.
+func (h *queryHistory) record(tool, db, table string, filter map[string]interface{}, orderBy string) {
+	pattern := QueryPattern{Tool: tool, OrderBy: orderBy}
+	pattern.EqualityFields, pattern.RangeFields = filterFields(filter)
+	if len(pattern.EqualityFields) == 0 && len(pattern.RangeFields) == 0 && orderBy == "" {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.patterns == nil {
+		h.patterns = make(map[string]map[string]*QueryPattern)
+	}
+	tableKey := db + "." + table
+	byKey := h.patterns[tableKey]
+	if byKey == nil {
+		byKey = make(map[string]*QueryPattern)
+		h.patterns[tableKey] = byKey
+	}
+	key := pattern.key()
+	stats, ok := byKey[key]
+	if !ok {
+		if h.total >= maxQueryPatterns {
+			h.evictOldest()
+		}
+		stats = &pattern
+		byKey[key] = stats
+		h.total++
+	}
+	stats.Count++
+	stats.LastSeen = time.Now().UTC()
+}
+
+func (h *queryHistory) evictOldest() {
+	var oldestTable, oldestKey string
+	var oldest time.Time
+	for tableKey, byKey := range h.patterns {
+		for key, stats := range byKey {
+			if oldestKey == "" || stats.LastSeen.Before(oldest) {
+				oldestTable, oldestKey, oldest = tableKey, key, stats.LastSeen
+			}
+		}
+	}
+	if oldestKey == "" {
+		return
+	}
+	delete(h.patterns[oldestTable], oldestKey)
+	if len(h.patterns[oldestTable]) == 0 {
+		delete(h.patterns, oldestTable)
+	}
+	h.total--
+}
+
+// forTable returns a copy of the patterns recorded for db.table.
+func (h *queryHistory) forTable(db, table string) []QueryPattern {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	byKey := h.patterns[db+"."+table]
+	patterns := make([]QueryPattern, 0, len(byKey))
+	for _, stats := range byKey {
+		patterns = append(patterns, *stats)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Count != patterns[j].Count {
+			return patterns[i].Count > patterns[j].Count
+		}
+		return patterns[i].key() < patterns[j].key()
+	})
+	return patterns
+}
+
+// filterFields returns the sorted top-level fields a filter compares for
+// equality with a string, number or boolean (a plain value or $eq), and by
+// range ($gt, $gte, $lt, $lte). These are the fields an index lookup can
+// serve; nested paths and fields only reachable through $and, $or, $not,
+// $in, $ne, $nin, $regex or $exists are left out.
+func filterFields(filter map[string]interface{}) (equality, rng []string) {
+	eq := map[string]bool{}
+	rg := map[string]bool{}
+	for field, value := range filter {
+		if strings.HasPrefix(field, "$") || strings.Contains(field, ".") {
+			continue
+		}
+		if _, ok := equalityValue(value); ok {
+			eq[field] = true
+			continue
+		}
+		ops, _ := value.(map[string]interface{})
+		for op := range ops {
+			switch op {
+			case "$gt", "$gte", "$lt", "$lte":
+				rg[field] = true
+			}
+		}
+	}
+	return sortedSet(eq), sortedSet(rg)
+}
+
+func sortedSet(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	list := make([]string, 0, len(set))
+	for item := range set {
+		list = append(list, item)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// ─── Input/Output structs ────────────────────────────────────────────────────
+
+type SuggestIndexesInput struct {
+	Database  string `json:"database" jsonschema:"The database name"`
+	Table     string `json:"table" jsonschema:"The table name"`
+	TimeoutMs int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+// IndexSuggestion is a recommended index and the recorded queries that would
+// use it. Create it with index_create and the same name.
+type IndexSuggestion struct {
+	Index    string   `json:"index"`
+	Fields   []string `json:"fields"`
+	Compound bool     `json:"compound"`
+	// QueryCount is how many recorded calls would benefit.
+	QueryCount int            `json:"query_count"`
+	Queries    []QueryPattern `json:"queries"`
+	// EstimatedDocsScanned is how many documents each of those calls reads
+	// today, assuming a full table scan.
+	EstimatedDocsScanned int    `json:"estimated_docs_scanned"`
+	Reason               string `json:"reason"`
+}
+
+type SuggestIndexesOutput struct {
+	Database        string            `json:"database"`
+	Table           string            `json:"table"`
+	DocCount        int               `json:"doc_count"`
+	ExistingIndexes []string          `json:"existing_indexes"`
+	RecordedQueries int               `json:"recorded_queries"`
+	Suggestions     []IndexSuggestion `json:"suggestions"`
+}
+
+// ─── Handler ─────────────────────────────────────────────────────────────────
+
+// SuggestIndexes recommends simple and compound secondary indexes for the
+// filter and order_by fields recorded from earlier query_table and
+// aggregate calls on a table. An existing index covers a suggestion when it
+// is keyed by the same fields, whatever its name.
+func (s *RethinkDBServer) SuggestIndexes(ctx context.Context, req *mcp.CallToolRequest, input SuggestIndexesInput) (*mcp.CallToolResult, SuggestIndexesOutput, error) {
+	if input.Database == "" || input.Table == "" {
+		return nil, SuggestIndexesOutput{}, fmt.Errorf("database and table names are required")
+	}
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, SuggestIndexesOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "suggest_indexes", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("suggest_indexes on %s.%s", input.Database, input.Table)
+
+	table := r.DB(input.Database).Table(input.Table)
+	indexes, indexed, err := s.indexedKeys(ctx, input.Database, input.Table)
+	if err != nil {
+		return nil, SuggestIndexesOutput{}, queryError(ctx, what, err)
+	}
+
+	pk, err := s.primaryKey(ctx, input.Database, input.Table)
+	if err != nil {
+		return nil, SuggestIndexesOutput{}, queryError(ctx, what, err)
+	}
+
+	countCursor, err := table.Count().Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, SuggestIndexesOutput{}, queryError(ctx, what, fmt.Errorf("failed to count documents: %w", err))
+	}
+	defer countCursor.Close()
+	var docCount int
+	if err := countCursor.One(&docCount); err != nil {
+		return nil, SuggestIndexesOutput{}, queryError(ctx, what, fmt.Errorf("failed to read document count: %w", err))
+	}
+
+	patterns := s.history.forTable(input.Database, input.Table)
+	recorded := 0
+	for _, pattern := range patterns {
+		recorded += pattern.Count
+	}
+
+	return nil, SuggestIndexesOutput{
+		Database:        input.Database,
+		Table:           input.Table,
+		DocCount:        docCount,
+		ExistingIndexes: indexes,
+		RecordedQueries: recorded,
+		Suggestions:     suggestIndexes(patterns, indexed, pk, docCount),
+	}, nil
+}
+
+// indexedKeys returns the names of the secondary indexes of db.table and
+// the keys of those on top-level fields: their fields joined with commas.
+// Indexes are matched by key rather than name, so an index on a field
+// under another name still counts.
+func (s *RethinkDBServer) indexedKeys(ctx context.Context, db, table string) ([]string, map[string]bool, error) {
+	cursor, err := r.DB(db).Table(table).IndexStatus().Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get index status: %w", err)
+	}
+	defer cursor.Close()
+
+	var statuses []map[string]interface{}
+	if err := cursor.All(&statuses); err != nil {
+		return nil, nil, fmt.Errorf("failed to read index status: %w", err)
+	}
+
+	names := make([]string, 0, len(statuses))
+	indexed := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		names = append(names, indexDetail(status).Name)
+		if fields, ok := indexKeyFields(status); ok {
+			indexed[strings.Join(fields, ",")] = true
+		}
+	}
+	sort.Strings(names)
+	return names, indexed, nil
+}
+
+// suggestIndexes derives index suggestions from recorded query_table and
+// aggregate patterns, skipping those an index already covers. Equality
+// fields come first in a compound index, followed by one range or ordering
+// field, so the index can serve both GetAll and Between. indexed holds the
+// keys of existing indexes, as returned by indexedKeys.
+func suggestIndexes(patterns []QueryPattern, indexed map[string]bool, pk string, docCount int) []IndexSuggestion {
+	byName := map[string]*IndexSuggestion{}
+	var order []string
+	for _, pattern := range patterns {
+		fields := append([]string{}, pattern.EqualityFields...)
+		var reason string
+		switch {
+		case len(pattern.RangeFields) > 0:
+			fields = append(fields, pattern.RangeFields[0])
+			reason = "range filter"
+		case pattern.OrderBy != "" && !strings.Contains(pattern.OrderBy, ".") && !containsString(fields, pattern.OrderBy):
+			fields = append(fields, pattern.OrderBy)
+			reason = "order_by"
+		}
+		if len(pattern.EqualityFields) > 0 {
+			if reason != "" {
+				reason = "equality filter followed by " + reason
+			} else {
+				reason = "equality filter"
+			}
+		}
+		key := strings.Join(fields, ",")
+		if len(fields) == 0 || key == pk || indexed[key] {
+			continue
+		}
+		switch {
+		case len(fields) > 1:
+			reason += ": read the matching documents with advanced_query between on this compound index instead of scanning the table"
+		case len(pattern.EqualityFields) > 0:
+			reason += ": query_table looks matching documents up with get_all on this index instead of scanning the table"
+		case len(pattern.RangeFields) > 0:
+			reason += ": advanced_query between on this index reads only the matching range"
+		default:
+			reason += ": query_table reads the table in this index's order instead of sorting every document"
+		}
+
+		name := strings.Join(fields, "_")
+		suggestion, ok := byName[name]
+		if !ok {
+			suggestion = &IndexSuggestion{
+				Index:                name,
+				Fields:               fields,
+				Compound:             len(fields) > 1,
+				EstimatedDocsScanned: docCount,
+				Reason:               reason,
+			}
+			byName[name] = suggestion
+			order = append(order, name)
+		}
+		suggestion.QueryCount += pattern.Count
+		suggestion.Queries = append(suggestion.Queries, pattern)
+	}
+
+	suggestions := make([]IndexSuggestion, 0, len(order))
+	for _, name := range order {
+		suggestions = append(suggestions, *byName[name])
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].QueryCount > suggestions[j].QueryCount
+	})
+	return suggestions
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ─── query history and index suggestions ────────────────────────────────────
+
+func TestFilterFields(t *testing.T) {
+	eq, rng := filterFields(map[string]interface{}{
+		"status":  "active",
+		"age":     map[string]interface{}{"$gte": 18, "$lt": 65},
+		"address": map[string]interface{}{"city": "Paris"},
+		"role":    map[string]interface{}{"$in": []interface{}{"admin", "owner"}},
+		"name":    map[string]interface{}{"$regex": "^A"},
+		"$and":    []interface{}{map[string]interface{}{"team": "core"}},
+		"$or":     []interface{}{map[string]interface{}{"vip": true}},
+	})
+	if want := []string{"status"}; !reflect.DeepEqual(eq, want) {
+		t.Errorf("expected equality fields %v, got %v", want, eq)
+	}
+	if want := []string{"age"}; !reflect.DeepEqual(rng, want) {
+		t.Errorf("expected range fields %v, got %v", want, rng)
+	}
+}
+
+func TestQueryHistory_Record(t *testing.T) {
+	var h queryHistory
+	h.record("query_table", "db", "t", map[string]interface{}{"status": "active"}, "")
+	h.record("query_table", "db", "t", map[string]interface{}{"status": "inactive"}, "")
+	h.record("query_table", "db", "t", nil, "")
+	h.record("aggregate", "db", "other", map[string]interface{}{"age": 3}, "")
+
+	patterns := h.forTable("db", "t")
+	if len(patterns) != 1 {
+		t.Fatalf("expected 1 pattern, got %d", len(patterns))
+	}
+	if patterns[0].Count != 2 || !reflect.DeepEqual(patterns[0].EqualityFields, []string{"status"}) {
+		t.Errorf("unexpected pattern: %+v", patterns[0])
+	}
+}
+
+func TestQueryHistory_EvictsOldest(t *testing.T) {
+	var h queryHistory
+	for i := 0; i <= maxQueryPatterns; i++ {
+		h.record("query_table", "db", "t", nil, string(rune('a'+i%26))+string(rune('a'+i/26)))
+	}
+	if h.total != maxQueryPatterns {
+		t.Errorf("expected %d patterns, got %d", maxQueryPatterns, h.total)
+	}
+}
+
+func TestSuggestIndexes_Compound(t *testing.T) {
+	patterns := []QueryPattern{
+		{Tool: "query_table", EqualityFields: []string{"status"}, RangeFields: []string{"age"}, Count: 5},
+		{Tool: "query_table", EqualityFields: []string{"status"}, Count: 3},
+		{Tool: "query_table", OrderBy: "created_at", Count: 1},
+		{Tool: "query_table", OrderBy: "address.city", Count: 2},
+		{Tool: "aggregate", EqualityFields: []string{"region"}, Count: 4},
+		{Tool: "aggregate", EqualityFields: []string{"id"}, Count: 7},
+	}
+	// indexed is keyed by fields, so status counts whatever its index is
+	// named, while the compound team,day index does not cover region.
+	indexed := map[string]bool{"status": true, "team,day": true}
+	suggestions := suggestIndexes(patterns, indexed, "id", 1000)
+	if len(suggestions) != 3 {
+		t.Fatalf("expected 3 suggestions, got %+v", suggestions)
+	}
+	first := suggestions[0]
+	if first.Index != "status_age" || !first.Compound || !reflect.DeepEqual(first.Fields, []string{"status", "age"}) {
+		t.Errorf("unexpected first suggestion: %+v", first)
+	}
+	if first.QueryCount != 5 || first.EstimatedDocsScanned != 1000 {
+		t.Errorf("unexpected counts: %+v", first)
+	}
+	if second := suggestions[1]; second.Index != "region" || second.Compound || second.Queries[0].Tool != "aggregate" {
+		t.Errorf("expected a region suggestion from aggregate, got %+v", second)
+	}
+	if suggestions[2].Index != "created_at" || suggestions[2].Compound {
+		t.Errorf("unexpected third suggestion: %+v", suggestions[2])
+	}
+}
+
+func TestIndexKeyFields(t *testing.T) {
+	compound := map[string]interface{}{"index": "by_name", "query": `indexCreate('by_name', function(var1) { return [var1("last"), var1("first")]; })`}
+	if fields, ok := indexKeyFields(compound); !ok || !reflect.DeepEqual(fields, []string{"last", "first"}) {
+		t.Errorf("expected by_name to be keyed by last, first, got %v, %v", fields, ok)
+	}
+	renamed := map[string]interface{}{"index": "by_age", "query": `indexCreate('by_age', function(var1) { return var1("age"); })`}
+	if fields, ok := indexKeyFields(renamed); !ok || !reflect.DeepEqual(fields, []string{"age"}) {
+		t.Errorf("expected by_age to be keyed by age, got %v, %v", fields, ok)
+	}
+	nested := map[string]interface{}{"index": "city", "query": `indexCreate('city', function(var1) { return [var1("address")("city")]; })`}
+	if _, ok := indexKeyFields(nested); ok {
+		t.Error("expected an index on a nested field to have no key fields")
+	}
+}
+
+func TestSuggestIndexes_FromQueryTable(t *testing.T) {
+	srv := newTestServer()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := srv.QueryTable(ctx, &mcp.CallToolRequest{}, QueryTableInput{
+			Database: testDB,
+			Table:    testTable,
+			Filter:   map[string]any{"name": "Alice"},
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// status is already indexed, so this call needs no new index.
+	if _, _, err := srv.QueryTable(ctx, &mcp.CallToolRequest{}, QueryTableInput{
+		Database: testDB,
+		Table:    testTable,
+		Filter:   map[string]any{"status": "active"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, output, err := srv.SuggestIndexes(ctx, &mcp.CallToolRequest{}, SuggestIndexesInput{
+		Database: testDB,
+		Table:    testTable,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.RecordedQueries != 3 {
+		t.Errorf("expected 3 recorded queries, got %d", output.RecordedQueries)
+	}
+	if output.DocCount != 3 {
+		t.Errorf("expected doc count 3, got %d", output.DocCount)
+	}
+	if len(output.Suggestions) != 1 || output.Suggestions[0].Index != "name" || output.Suggestions[0].QueryCount != 2 {
+		t.Errorf("expected a single suggestion for name, got %+v", output.Suggestions)
+	}
+}
@@ -0,0 +1,368 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// indexPollInterval is how often index_wait checks index status while
+// indexes are being built.
+const indexPollInterval = 500 * time.Millisecond
+
+// ─── Input/Output structs ────────────────────────────────────────────────────
+
+type IndexCreateInput struct {
+	Database   string   `json:"database" jsonschema:"The database name"`
+	Table      string   `json:"table" jsonschema:"The table name"`
+	Index      string   `json:"index" jsonschema:"Name of the index to create"`
+	Fields     []string `json:"fields,omitempty" jsonschema:"Fields to index; several fields make a compound index. Dotted paths select nested fields. Defaults to the field named like the index"`
+	Expression string   `json:"expression,omitempty" jsonschema:"ReQL function to index instead of fields, e.g. r.row('email').downcase() or function (doc) { return [doc('last'), doc('first')]; }"`
+	Multi      bool     `json:"multi,omitempty" jsonschema:"Create a multi index: each element of an array value is indexed separately"`
+	Geo        bool     `json:"geo,omitempty" jsonschema:"Create a geospatial index on a geometry field"`
+	Wait       bool     `json:"wait,omitempty" jsonschema:"Wait for the index to be ready before returning, reporting progress"`
+	TimeoutMs  int      `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+type IndexCreateOutput struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	Index    string `json:"index"`
+	Created  bool   `json:"created"`
+	Ready    bool   `json:"ready"`
+}
+
+type IndexDropInput struct {
+	Database  string `json:"database" jsonschema:"The database name"`
+	Table     string `json:"table" jsonschema:"The table name"`
+	Index     string `json:"index" jsonschema:"Name of the index to drop"`
+	TimeoutMs int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+type IndexDropOutput struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	Index    string `json:"index"`
+	Dropped  bool   `json:"dropped"`
+}
+
+type IndexRenameInput struct {
+	Database  string `json:"database" jsonschema:"The database name"`
+	Table     string `json:"table" jsonschema:"The table name"`
+	Index     string `json:"index" jsonschema:"Current index name"`
+	NewName   string `json:"new_name" jsonschema:"New index name"`
+	Overwrite bool   `json:"overwrite,omitempty" jsonschema:"Replace an existing index named new_name instead of failing"`
+	TimeoutMs int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+type IndexRenameOutput struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	Index    string `json:"index"`
+	NewName  string `json:"new_name"`
+	Renamed  bool   `json:"renamed"`
+}
+
+type IndexWaitInput struct {
+	Database  string   `json:"database" jsonschema:"The database name"`
+	Table     string   `json:"table" jsonschema:"The table name"`
+	Indexes   []string `json:"indexes,omitempty" jsonschema:"Indexes to wait for (default all)"`
+	TimeoutMs int      `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+type IndexWaitOutput struct {
+	Database string        `json:"database"`
+	Table    string        `json:"table"`
+	Indexes  []IndexDetail `json:"indexes"`
+	WaitedMs int64         `json:"waited_ms"`
+}
+
+// ─── Handlers ────────────────────────────────────────────────────────────────
+
+func (s *RethinkDBServer) IndexCreate(ctx context.Context, req *mcp.CallToolRequest, input IndexCreateInput) (*mcp.CallToolResult, IndexCreateOutput, error) {
+	if input.Database == "" || input.Table == "" || input.Index == "" {
+		return nil, IndexCreateOutput{}, fmt.Errorf("database, table and index names are required")
+	}
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, IndexCreateOutput{}, err
+	}
+	if err := s.checkReservedTables(input.Database, input.Table); err != nil {
+		return nil, IndexCreateOutput{}, err
+	}
+
+	fn, err := indexFunction(input.Index, input.Fields, input.Expression)
+	if err != nil {
+		return nil, IndexCreateOutput{}, err
+	}
+	if input.Geo && len(input.Fields) > 1 {
+		return nil, IndexCreateOutput{}, fmt.Errorf("a geo index cannot be compound")
+	}
+
+	opts := r.IndexCreateOpts{}
+	if input.Multi {
+		opts.Multi = true
+	}
+	if input.Geo {
+		opts.Geo = true
+	}
+
+	table := r.DB(input.Database).Table(input.Table)
+	query := table.IndexCreate(input.Index, opts)
+	if fn != nil {
+		query = table.IndexCreateFunc(input.Index, fn, opts)
+	}
+	if err := s.checkReadOnly(query); err != nil {
+		return nil, IndexCreateOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "index_create", input.TimeoutMs)
+	defer cancel()
+	what := describeQuery(fmt.Sprintf("index_create on %s.%s", input.Database, input.Table), query)
+
+	resp, err := query.RunWrite(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, IndexCreateOutput{}, queryError(ctx, what, fmt.Errorf("failed to create index: %w", err))
+	}
+
+	output := IndexCreateOutput{
+		Database: input.Database,
+		Table:    input.Table,
+		Index:    input.Index,
+		Created:  resp.Created == 1,
+	}
+	if input.Wait {
+		indexes, err := s.waitForIndexes(ctx, req, input.Database, input.Table, []string{input.Index})
+		if err != nil {
+			return nil, IndexCreateOutput{}, queryError(ctx, what, err)
+		}
+		output.Ready = len(indexes) == 1 && indexes[0].Ready
+	}
+
+	return nil, output, nil
+}
+
+func (s *RethinkDBServer) IndexDrop(ctx context.Context, req *mcp.CallToolRequest, input IndexDropInput) (*mcp.CallToolResult, IndexDropOutput, error) {
+	if input.Database == "" || input.Table == "" || input.Index == "" {
+		return nil, IndexDropOutput{}, fmt.Errorf("database, table and index names are required")
+	}
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, IndexDropOutput{}, err
+	}
+	if err := s.checkReservedTables(input.Database, input.Table); err != nil {
+		return nil, IndexDropOutput{}, err
+	}
+
+	query := r.DB(input.Database).Table(input.Table).IndexDrop(input.Index)
+	if err := s.checkReadOnly(query); err != nil {
+		return nil, IndexDropOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "index_drop", input.TimeoutMs)
+	defer cancel()
+	what := describeQuery(fmt.Sprintf("index_drop on %s.%s", input.Database, input.Table), query)
+
+	resp, err := query.RunWrite(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, IndexDropOutput{}, queryError(ctx, what, fmt.Errorf("failed to drop index: %w", err))
+	}
+
+	return nil, IndexDropOutput{
+		Database: input.Database,
+		Table:    input.Table,
+		Index:    input.Index,
+		Dropped:  resp.Dropped == 1,
+	}, nil
+}
+
+func (s *RethinkDBServer) IndexRename(ctx context.Context, req *mcp.CallToolRequest, input IndexRenameInput) (*mcp.CallToolResult, IndexRenameOutput, error) {
+	if input.Database == "" || input.Table == "" || input.Index == "" || input.NewName == "" {
+		return nil, IndexRenameOutput{}, fmt.Errorf("database, table, index and new_name are required")
+	}
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, IndexRenameOutput{}, err
+	}
+	if err := s.checkReservedTables(input.Database, input.Table); err != nil {
+		return nil, IndexRenameOutput{}, err
+	}
+
+	query := r.DB(input.Database).Table(input.Table).IndexRename(input.Index, input.NewName, r.IndexRenameOpts{Overwrite: input.Overwrite})
+	if err := s.checkReadOnly(query); err != nil {
+		return nil, IndexRenameOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "index_rename", input.TimeoutMs)
+	defer cancel()
+	what := describeQuery(fmt.Sprintf("index_rename on %s.%s", input.Database, input.Table), query)
+
+	resp, err := query.RunWrite(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, IndexRenameOutput{}, queryError(ctx, what, fmt.Errorf("failed to rename index: %w", err))
+	}
+
+	return nil, IndexRenameOutput{
+		Database: input.Database,
+		Table:    input.Table,
+		Index:    input.Index,
+		NewName:  input.NewName,
+		Renamed:  resp.Renamed == 1,
+	}, nil
+}
+
+// IndexWait blocks until the given indexes are ready. When the client sends
+// a progress token, build progress is reported with progress notifications.
+func (s *RethinkDBServer) IndexWait(ctx context.Context, req *mcp.CallToolRequest, input IndexWaitInput) (*mcp.CallToolResult, IndexWaitOutput, error) {
+	if input.Database == "" || input.Table == "" {
+		return nil, IndexWaitOutput{}, fmt.Errorf("database and table names are required")
+	}
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, IndexWaitOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "index_wait", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("index_wait on %s.%s", input.Database, input.Table)
+
+	start := time.Now()
+	indexes, err := s.waitForIndexes(ctx, req, input.Database, input.Table, input.Indexes)
+	if err != nil {
+		return nil, IndexWaitOutput{}, queryError(ctx, what, err)
+	}
+
+	return nil, IndexWaitOutput{
+		Database: input.Database,
+		Table:    input.Table,
+		Indexes:  indexes,
+		WaitedMs: time.Since(start).Milliseconds(),
+	}, nil
+}
+
+// ─── Index helpers ───────────────────────────────────────────────────────────
+
+// indexFunction returns the function to index for the given fields or
+// expression, or nil when the index is on the field named like the index.
+func indexFunction(name string, fields []string, expression string) (interface{}, error) {
+	if expression != "" {
+		if len(fields) > 0 {
+			return nil, fmt.Errorf("fields and expression are mutually exclusive")
+		}
+		fn, err := parseReQLFunction(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression: %w", err)
+		}
+		return fn, nil
+	}
+
+	if len(fields) == 0 || (len(fields) == 1 && fields[0] == name) {
+		return nil, nil
+	}
+	paths := make([][]string, len(fields))
+	for i, field := range fields {
+		path, err := fieldPath(nil, field)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = path
+	}
+	if len(paths) == 1 {
+		return func(row r.Term) r.Term { return fieldTerm(row, paths[0]) }, nil
+	}
+	return func(row r.Term) r.Term {
+		values := make([]interface{}, len(paths))
+		for i, path := range paths {
+			values[i] = fieldTerm(row, path)
+		}
+		return r.Expr(values)
+	}, nil
+}
+
+// waitForIndexes polls index status until every requested index (all when
+// names is empty) is ready, sending progress notifications to the caller.
+func (s *RethinkDBServer) waitForIndexes(ctx context.Context, req *mcp.CallToolRequest, db, table string, names []string) ([]IndexDetail, error) {
+	var token any
+	if req != nil && req.Params != nil {
+		token = req.Params.GetProgressToken()
+	}
+
+	ticker := time.NewTicker(indexPollInterval)
+	defer ticker.Stop()
+
+	for {
+		args := make([]interface{}, len(names))
+		for i, name := range names {
+			args[i] = name
+		}
+		cursor, err := r.DB(db).Table(table).IndexStatus(args...).Run(s.session, r.RunOpts{Context: ctx})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get index status: %w", err)
+		}
+		var statuses []map[string]interface{}
+		err = cursor.All(&statuses)
+		cursor.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read index status: %w", err)
+		}
+
+		details := make([]IndexDetail, 0, len(statuses))
+		var progress float64
+		var building []string
+		for _, status := range statuses {
+			detail := indexDetail(status)
+			details = append(details, detail)
+			if detail.Ready {
+				progress++
+				continue
+			}
+			building = append(building, detail.Name)
+			if p, ok := status["progress"].(float64); ok {
+				progress += p
+			}
+		}
+
+		if token != nil && req.Session != nil {
+			message := "all indexes ready"
+			if len(building) > 0 {
+				message = "building " + strings.Join(building, ", ")
+			}
+			req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+				ProgressToken: token,
+				Progress:      progress,
+				Total:         float64(len(statuses)),
+				Message:       message,
+			})
+		}
+		if len(building) == 0 {
+			return details, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("indexes not ready: %s: %w", strings.Join(building, ", "), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// indexDetail converts an index_status row into an IndexDetail.
+func indexDetail(status map[string]interface{}) IndexDetail {
+	detail := IndexDetail{}
+	if name, ok := status["index"].(string); ok {
+		detail.Name = name
+	}
+	if ready, ok := status["ready"].(bool); ok {
+		detail.Ready = ready
+	}
+	if multi, ok := status["multi"].(bool); ok {
+		detail.Multi = multi
+	}
+	if geo, ok := status["geo"].(bool); ok {
+		detail.Geo = geo
+	}
+	if outdated, ok := status["outdated"].(bool); ok {
+		detail.Outdated = outdated
+	}
+	return detail
+}
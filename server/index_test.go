@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── index management ───────────────────────────────────────────────────────
+
+func TestIndexFunction(t *testing.T) {
+	if fn, err := indexFunction("status", nil, ""); err != nil || fn != nil {
+		t.Errorf("expected a plain field index, got %v, %v", fn, err)
+	}
+	if fn, err := indexFunction("status", []string{"status"}, ""); err != nil || fn != nil {
+		t.Errorf("expected a plain field index, got %v, %v", fn, err)
+	}
+	if fn, err := indexFunction("city", []string{"address.city"}, ""); err != nil || fn == nil {
+		t.Errorf("expected a function index for a nested field, got %v, %v", fn, err)
+	}
+	if fn, err := indexFunction("name", nil, "r.row('name').downcase()"); err != nil || fn == nil {
+		t.Errorf("expected a function index, got %v, %v", fn, err)
+	}
+	if _, err := indexFunction("name", []string{"name"}, "r.row('name')"); err == nil {
+		t.Error("expected fields and expression to be mutually exclusive")
+	}
+	if _, err := indexFunction("bad", []string{"a..b"}, ""); err == nil {
+		t.Error("expected an invalid field path to be rejected")
+	}
+}
+
+func TestParseReQLFunction(t *testing.T) {
+	valid := []string{
+		"r.row('email').downcase()",
+		"function (doc) { return [doc('last'), doc('first')]; }",
+		"doc => doc('tags')",
+	}
+	for _, src := range valid {
+		if _, err := parseReQLFunction(src); err != nil {
+			t.Errorf("%s: unexpected error: %v", src, err)
+		}
+	}
+
+	invalid := []string{
+		"r.db('app').table('users').count()",
+		"(a, b) => a.add(b)",
+		"r.expr(5)",
+		"r.js('1')",
+	}
+	for _, src := range invalid {
+		if _, err := parseReQLFunction(src); err == nil {
+			t.Errorf("%s: expected an error", src)
+		}
+	}
+}
+
+func TestIndexCreate_CompoundAndWait(t *testing.T) {
+	srv := newTestServer()
+	ctx := context.Background()
+	defer r.DB(testDB).Table(testJoinTable).IndexDrop("dept_id").RunWrite(testSession)
+
+	_, output, err := srv.IndexCreate(ctx, &mcp.CallToolRequest{}, IndexCreateInput{
+		Database: testDB,
+		Table:    testJoinTable,
+		Index:    "dept_id",
+		Fields:   []string{"department", "id"},
+		Wait:     true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !output.Created || !output.Ready {
+		t.Errorf("expected a created, ready index, got %+v", output)
+	}
+
+	cursor, err := r.DB(testDB).Table(testJoinTable).GetAll([]interface{}{"Engineering", "3"}, r.GetAllOpts{Index: "dept_id"}).Run(testSession)
+	if err != nil {
+		t.Fatalf("compound index lookup failed: %v", err)
+	}
+	var docs []map[string]interface{}
+	cursor.All(&docs)
+	if len(docs) != 1 || docs[0]["id"] != "3" {
+		t.Errorf("expected document 3 from the compound index, got %v", docs)
+	}
+}
+
+func TestIndexCreate_Expression(t *testing.T) {
+	srv := newTestServer()
+	ctx := context.Background()
+	defer r.DB(testDB).Table(testJoinTable).IndexDrop("dept_lower").RunWrite(testSession)
+
+	_, _, err := srv.IndexCreate(ctx, &mcp.CallToolRequest{}, IndexCreateInput{
+		Database:   testDB,
+		Table:      testJoinTable,
+		Index:      "dept_lower",
+		Expression: "r.row('department').downcase()",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, waited, err := srv.IndexWait(ctx, &mcp.CallToolRequest{}, IndexWaitInput{
+		Database: testDB,
+		Table:    testJoinTable,
+		Indexes:  []string{"dept_lower"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error waiting: %v", err)
+	}
+	if len(waited.Indexes) != 1 || !waited.Indexes[0].Ready {
+		t.Errorf("expected dept_lower to be ready, got %+v", waited.Indexes)
+	}
+}
+
+func TestIndexRenameAndDrop(t *testing.T) {
+	srv := newTestServer()
+	ctx := context.Background()
+	table := r.DB(testDB).Table(testJoinTable)
+	table.IndexCreate("department").RunWrite(testSession)
+	table.IndexCreate("dept").RunWrite(testSession)
+	defer table.IndexDrop("dept").RunWrite(testSession)
+	defer table.IndexDrop("department").RunWrite(testSession)
+
+	_, _, err := srv.IndexRename(ctx, &mcp.CallToolRequest{}, IndexRenameInput{
+		Database: testDB,
+		Table:    testJoinTable,
+		Index:    "department",
+		NewName:  "dept",
+	})
+	if err == nil {
+		t.Fatal("expected renaming onto an existing index to fail without overwrite")
+	}
+
+	_, renamed, err := srv.IndexRename(ctx, &mcp.CallToolRequest{}, IndexRenameInput{
+		Database:  testDB,
+		Table:     testJoinTable,
+		Index:     "department",
+		NewName:   "dept",
+		Overwrite: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !renamed.Renamed {
+		t.Error("expected the index to be renamed")
+	}
+
+	_, dropped, err := srv.IndexDrop(ctx, &mcp.CallToolRequest{}, IndexDropInput{
+		Database: testDB,
+		Table:    testJoinTable,
+		Index:    "dept",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !dropped.Dropped {
+		t.Error("expected the index to be dropped")
+	}
+}
+
+func TestIndexCreate_ReadOnly(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithReadOnly(true))
+	_, _, err := srv.IndexCreate(context.Background(), &mcp.CallToolRequest{}, IndexCreateInput{
+		Database: testDB,
+		Table:    testJoinTable,
+		Index:    "never_created",
+	})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// fieldUpdate computes the new value of the field at path from the
+// document being updated.
+type fieldUpdate struct {
+	path  []string
+	value func(row r.Term) interface{}
+}
+
+// hasUpdateOperators reports whether doc uses update operators rather than
+// being a plain document to merge.
+func hasUpdateOperators(doc map[string]interface{}) bool {
+	for key := range doc {
+		if strings.HasPrefix(key, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// compileUpdate compiles an update document into a function for Update,
+// so that every operator is applied to a document in one atomic write. A
+// plain document is merged as is. Otherwise every key must be an operator:
+//
+//	$set         {"path": value}    set a field; objects replace, not merge
+//	$inc         {"path": n}        add n to a number, treating missing as 0
+//	$unset       {"path": true}     remove a field (or an array of paths)
+//	$push        {"path": value}    append to an array; {"$each": [...]} appends several
+//	$pull        {"path": value}    remove every element equal to value
+//	$currentTime {"path": true}     set a field to the time of the write
+//
+// Paths may be dotted ("address.city"). Two operators may not touch the
+// same field or a field and one nested inside it.
+func compileUpdate(doc map[string]interface{}) (func(r.Term) interface{}, error) {
+	if !hasUpdateOperators(doc) {
+		return func(r.Term) interface{} { return doc }, nil
+	}
+
+	var updates []fieldUpdate
+	for _, op := range sortedKeys(doc) {
+		fields, err := operatorFields(op, doc[op])
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range sortedKeys(fields) {
+			path, err := fieldPath(nil, key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := operatorValue(op, path, fields[key])
+			if err != nil {
+				return nil, err
+			}
+			updates = append(updates, fieldUpdate{path: path, value: value})
+		}
+	}
+	if err := checkOverlap(updates); err != nil {
+		return nil, err
+	}
+
+	return func(row r.Term) interface{} {
+		patch := make(map[string]interface{})
+		for _, u := range updates {
+			node := patch
+			for _, part := range u.path[:len(u.path)-1] {
+				child, ok := node[part].(map[string]interface{})
+				if !ok {
+					child = make(map[string]interface{})
+					node[part] = child
+				}
+				node = child
+			}
+			node[u.path[len(u.path)-1]] = u.value(row)
+		}
+		return patch
+	}, nil
+}
+
+// operatorFields returns the fields an operator applies to. $unset and
+// $currentTime also take an array of paths or a single path.
+func operatorFields(op string, arg interface{}) (map[string]interface{}, error) {
+	if !strings.HasPrefix(op, "$") {
+		return nil, fmt.Errorf("field %q cannot be mixed with update operators; use $set", op)
+	}
+	switch arg := arg.(type) {
+	case map[string]interface{}:
+		if len(arg) == 0 {
+			return nil, fmt.Errorf("%s requires at least one field", op)
+		}
+		return arg, nil
+	case []interface{}, string:
+		if op != "$unset" && op != "$currentTime" {
+			break
+		}
+		paths, ok := arg.([]interface{})
+		if !ok {
+			paths = []interface{}{arg}
+		}
+		fields := make(map[string]interface{}, len(paths))
+		for _, p := range paths {
+			name, ok := p.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s requires field names", op)
+			}
+			fields[name] = true
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%s requires at least one field", op)
+		}
+		return fields, nil
+	}
+	return nil, fmt.Errorf("%s requires an object of fields", op)
+}
+
+// operatorValue returns how op computes the new value of the field at path.
+func operatorValue(op string, path []string, arg interface{}) (func(r.Term) interface{}, error) {
+	switch op {
+	case "$set":
+		if _, isObject := arg.(map[string]interface{}); isObject {
+			// Update merges nested objects; a literal replaces the field.
+			return func(r.Term) interface{} { return r.Literal(arg) }, nil
+		}
+		return func(r.Term) interface{} { return arg }, nil
+
+	case "$inc":
+		n, ok := arg.(float64)
+		if !ok {
+			return nil, fmt.Errorf("$inc requires a number for %q", strings.Join(path, "."))
+		}
+		return func(row r.Term) interface{} {
+			return fieldTerm(row, path).Default(0).Add(n)
+		}, nil
+
+	case "$unset":
+		return func(r.Term) interface{} { return r.Literal() }, nil
+
+	case "$push":
+		if each, ok := arg.(map[string]interface{}); ok {
+			items, isArray := each["$each"].([]interface{})
+			if len(each) != 1 || !isArray {
+				return nil, fmt.Errorf("$push on %q takes a value or {\"$each\": [...]}", strings.Join(path, "."))
+			}
+			return func(row r.Term) interface{} {
+				return fieldTerm(row, path).Default([]interface{}{}).Add(items)
+			}, nil
+		}
+		return func(row r.Term) interface{} {
+			return fieldTerm(row, path).Default([]interface{}{}).Append(arg)
+		}, nil
+
+	case "$pull":
+		return func(row r.Term) interface{} {
+			return fieldTerm(row, path).Default([]interface{}{}).Filter(func(item r.Term) r.Term {
+				return item.Ne(arg)
+			})
+		}, nil
+
+	case "$currentTime":
+		return func(r.Term) interface{} { return r.Now() }, nil
+	}
+	return nil, fmt.Errorf("unknown update operator %q", op)
+}
+
+// checkOverlap rejects updates where one field is the same as, or nested
+// inside, another.
+func checkOverlap(updates []fieldUpdate) error {
+	for i, a := range updates {
+		for _, b := range updates[i+1:] {
+			n := len(a.path)
+			if len(b.path) < n {
+				n = len(b.path)
+			}
+			if strings.Join(a.path[:n], ".") == strings.Join(b.path[:n], ".") {
+				return fmt.Errorf("update operators conflict on %q and %q", strings.Join(a.path, "."), strings.Join(b.path, "."))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── write_data update operators ────────────────────────────────────────────
+
+func TestCompileUpdate_NestsPaths(t *testing.T) {
+	update, err := compileUpdate(map[string]interface{}{
+		"$set":   map[string]interface{}{"address.city": "Paris", "name": "Alice"},
+		"$inc":   map[string]interface{}{"stats.views": 1.0},
+		"$unset": []interface{}{"address.zip"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	patch := update(r.Row).(map[string]interface{})
+	address, ok := patch["address"].(map[string]interface{})
+	if !ok || address["city"] != "Paris" || address["zip"] == nil {
+		t.Errorf("expected address.city and address.zip to be nested, got %v", patch["address"])
+	}
+	if _, ok := patch["stats"].(map[string]interface{})["views"]; !ok || patch["name"] != "Alice" {
+		t.Errorf("unexpected patch: %v", patch)
+	}
+}
+
+func TestCompileUpdate_PlainDocument(t *testing.T) {
+	doc := map[string]interface{}{"name": "Alice"}
+	update, err := compileUpdate(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patch := update(r.Row).(map[string]interface{}); patch["name"] != "Alice" {
+		t.Errorf("expected the document to be merged as is, got %v", patch)
+	}
+}
+
+func TestCompileUpdate_Invalid(t *testing.T) {
+	invalid := []map[string]interface{}{
+		{"$set": map[string]interface{}{"a": 1.0}, "name": "mixed"},
+		{"$inc": map[string]interface{}{"a": "one"}},
+		{"$rename": map[string]interface{}{"a": "b"}},
+		{"$set": map[string]interface{}{"a": 1.0}, "$unset": "a"},
+		{"$set": map[string]interface{}{"a": map[string]interface{}{}}, "$inc": map[string]interface{}{"a.b": 1.0}},
+		{"$set": map[string]interface{}{"a..b": 1.0}},
+		{"$push": map[string]interface{}{"tags": map[string]interface{}{"$each": "x"}}},
+		{"$set": []interface{}{"a"}},
+		{"$inc": map[string]interface{}{}},
+	}
+	for _, doc := range invalid {
+		if _, err := compileUpdate(doc); err == nil {
+			t.Errorf("%v: expected an error", doc)
+		}
+	}
+}
+
+func TestWriteData_UpdateOperators(t *testing.T) {
+	srv := newTestServer()
+	r.DB(testDB).Table(testTable).Insert(map[string]interface{}{
+		"id": "ops_1", "views": 1, "tags": []string{"a", "b"}, "address": map[string]interface{}{"city": "Lyon", "zip": "69001"},
+	}).RunWrite(testSession)
+	defer r.DB(testDB).Table(testTable).Get("ops_1").Delete().RunWrite(testSession)
+
+	_, _, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Operation: "update",
+		Keys:      []any{"ops_1", "ops_missing"},
+		Data: json.RawMessage(`{
+			"$inc": {"views": 2},
+			"$set": {"address.city": "Paris"},
+			"$unset": ["address.zip"],
+			"$push": {"tags": {"$each": ["c", "d"]}},
+			"$pull": {"tags": "a"},
+			"$currentTime": ["updated_at"]
+		}`),
+	})
+	if err == nil {
+		t.Fatal("expected $push and $pull on the same field to conflict")
+	}
+
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Operation: "update",
+		Keys:      []any{"ops_1", "ops_missing"},
+		Data: json.RawMessage(`{
+			"$inc": {"views": 2},
+			"$set": {"address.city": "Paris"},
+			"$unset": ["address.zip"],
+			"$push": {"tags": {"$each": ["c", "d"]}},
+			"$currentTime": ["updated_at"]
+		}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Replaced != 1 || len(output.NotFound) != 1 {
+		t.Errorf("expected 1 replaced and ops_missing not found, got %+v", output)
+	}
+
+	cursor, _ := r.DB(testDB).Table(testTable).Get("ops_1").Run(testSession)
+	defer cursor.Close()
+	var doc map[string]interface{}
+	cursor.One(&doc)
+	address := doc["address"].(map[string]interface{})
+	if doc["views"] != 3.0 || address["city"] != "Paris" || address["zip"] != nil {
+		t.Errorf("unexpected document: %v", doc)
+	}
+	if tags := doc["tags"].([]interface{}); len(tags) != 4 {
+		t.Errorf("expected 4 tags, got %v", tags)
+	}
+	if _, ok := doc["updated_at"].(time.Time); !ok {
+		t.Errorf("expected updated_at to be a time, got %T", doc["updated_at"])
+	}
+}
+
+func TestWriteData_UpdateOperatorsByFilterDryRun(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Operation: "update",
+		Filter:    map[string]any{"status": "active"},
+		Data:      json.RawMessage(`{"$inc": {"age": 1}}`),
+		DryRun:    true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Replaced != 2 || len(output.Preview) != 2 {
+		t.Fatalf("expected 2 replacements previewed, got %+v", output)
+	}
+	for _, p := range output.Preview {
+		oldAge := p.Old.(map[string]interface{})["age"].(float64)
+		newAge := p.New.(map[string]interface{})["age"].(float64)
+		if newAge != oldAge+1 {
+			t.Errorf("expected age %v to become %v, got %v", oldAge, oldAge+1, newAge)
+		}
+	}
+}
+
+func TestWriteData_UpdateOperatorsRequireSelection(t *testing.T) {
+	srv := newTestServer()
+	_, _, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Operation: "update",
+		Data:      json.RawMessage(`{"$inc": {"age": 1}}`),
+	})
+	if err == nil {
+		t.Error("expected update operators without keys or filter to be rejected")
+	}
+
+	_, _, err = srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Operation: "update",
+		Filter:    map[string]any{},
+		Data:      json.RawMessage(`{"$inc": {"age": 1}}`),
+	})
+	if err == nil {
+		t.Error("expected an empty update filter to be rejected")
+	}
+}
+
+func TestWriteData_KeysAndFilterOnlyForUpdate(t *testing.T) {
+	srv := newTestServer()
+	for _, operation := range []string{"insert", "upsert", "delete", "compare_and_set"} {
+		_, _, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+			Database:  testDB,
+			Table:     testTable,
+			Operation: operation,
+			Filter:    map[string]any{"status": "inactive"},
+			Data:      json.RawMessage(`{}`),
+		})
+		if err == nil {
+			t.Errorf("%s: expected filter to be rejected", operation)
+		}
+	}
+}
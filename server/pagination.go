@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// pageCursor is the decoded form of the opaque next_cursor token. It records
+// where the previous page stopped so the next page can resume with Between
+// on an index instead of Skip.
+type pageCursor struct {
+	// Shape identifies the query the cursor was issued for; a cursor is
+	// rejected when replayed against a different query.
+	Shape string `json:"s"`
+	// Index is the index the pages are ordered by. Empty means the query is
+	// ordered by a plain field (OrderBy) rather than an index.
+	Index string `json:"i,omitempty"`
+	// Field is the ordering field when Index is empty.
+	Field string `json:"f,omitempty"`
+	// Value is the last index or field value returned. It is unused when
+	// ordering by the primary key.
+	Value interface{} `json:"v,omitempty"`
+	// Key is the primary key of the last document returned.
+	Key interface{} `json:"k"`
+}
+
+// queryShape returns a short stable fingerprint of the given query inputs.
+func queryShape(parts ...interface{}) string {
+	data, _ := json.Marshal(parts)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+func encodeCursor(c pageCursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor parses token and checks that it was issued for a query with
+// the given shape. An empty token decodes to nil.
+func decodeCursor(token, shape string) (*pageCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if c.Shape != shape {
+		return nil, fmt.Errorf("invalid cursor: it was issued for a different query")
+	}
+	return &c, nil
+}
+
+// pageByIndex returns table ordered by index, restricted to [lower, upper)
+// and resuming after the document recorded in after, if any. Ordering by a
+// secondary index visits documents with equal index values in primary key
+// order, so ties are broken by comparing the primary key; field is the
+// document field a secondary index is keyed by.
+func pageByIndex(table r.Term, index, field, pk string, lower, upper interface{}, after *pageCursor) r.Term {
+	if after == nil {
+		return table.Between(lower, upper, r.BetweenOpts{Index: index}).OrderBy(r.OrderByOpts{Index: index})
+	}
+	if index == pk {
+		return table.Between(after.Key, upper, r.BetweenOpts{Index: index, LeftBound: "open"}).
+			OrderBy(r.OrderByOpts{Index: index})
+	}
+	return table.Between(after.Value, upper, r.BetweenOpts{Index: index}).
+		OrderBy(r.OrderByOpts{Index: index}).
+		Filter(func(row r.Term) r.Term {
+			return row.Field(field).Ne(after.Value).Or(row.Field(pk).Gt(after.Key))
+		})
+}
+
+// pageByField returns query ordered by field (without an index), resuming
+// after the document recorded in after, if any.
+func pageByField(query r.Term, field, pk string, after *pageCursor) r.Term {
+	if after != nil {
+		query = query.Filter(func(row r.Term) r.Term {
+			return row.Field(field).Gt(after.Value).
+				Or(row.Field(field).Eq(after.Value).And(row.Field(pk).Gt(after.Key)))
+		})
+	}
+	if field == pk {
+		return query.OrderBy(pk)
+	}
+	return query.OrderBy(field, pk)
+}
+
+// nextCursor builds the token for the page that follows last. index is the
+// index the pages are ordered by, or empty when they are ordered by field;
+// for a secondary index, field is the document field the index is keyed by,
+// and empty when there is none to resume from.
+func nextCursor(last interface{}, shape, index, field, pk string) (string, error) {
+	doc, ok := last.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("failed to build next_cursor: result is not a document")
+	}
+	key, ok := doc[pk]
+	if !ok {
+		return "", fmt.Errorf("failed to build next_cursor: result has no primary key %q", pk)
+	}
+	c := pageCursor{Shape: shape, Index: index, Key: key}
+	switch {
+	case index == "":
+		c.Field = field
+		c.Value = doc[field]
+	case index != pk:
+		if field == "" {
+			return "", fmt.Errorf("cannot page through index %q: only the primary key and indexes on a single top-level field support next_cursor; narrow the bounds or raise the limit", index)
+		}
+		value, ok := doc[field]
+		if !ok {
+			return "", fmt.Errorf("failed to build next_cursor: result has no field %q", field)
+		}
+		c.Value = value
+	}
+	return encodeCursor(c)
+}
+
+// indexKeyField returns the top-level field whose value an index is keyed
+// by, read from the index's index_status row. Compound, multi, geo and
+// function indexes have no such field. Servers that do not report the
+// index query are assumed to name indexes after their field.
+func indexKeyField(status map[string]interface{}) (string, bool) {
+	detail := indexDetail(status)
+	if detail.Name == "" || detail.Multi || detail.Geo {
+		return "", false
+	}
+	query, ok := status["query"].(string)
+	if !ok {
+		return detail.Name, true
+	}
+	m := fieldIndexQuery.FindStringSubmatch(query)
+	if m == nil || m[1] != m[2] {
+		return "", false
+	}
+	field, err := strconv.Unquote(`"` + m[3] + `"`)
+	if err != nil {
+		return "", false
+	}
+	return field, true
+}
+
+// indexKeyFields returns the top-level fields an index is keyed by, in
+// order: one for a simple index, several for a compound one. Multi, geo and
+// function indexes, and compound indexes on anything but top-level fields,
+// have none.
+func indexKeyFields(status map[string]interface{}) ([]string, bool) {
+	if field, ok := indexKeyField(status); ok {
+		return []string{field}, true
+	}
+	detail := indexDetail(status)
+	query, ok := status["query"].(string)
+	if !ok || detail.Multi || detail.Geo {
+		return nil, false
+	}
+	m := compoundIndexQuery.FindStringSubmatch(query)
+	if m == nil {
+		return nil, false
+	}
+	var fields []string
+	for rest := m[2]; rest != ""; {
+		e := compoundIndexElement.FindStringSubmatch(rest)
+		if e == nil || e[1] != m[1] {
+			return nil, false
+		}
+		field, err := strconv.Unquote(`"` + e[2] + `"`)
+		if err != nil {
+			return nil, false
+		}
+		fields = append(fields, field)
+		rest = rest[len(e[0]):]
+	}
+	return fields, len(fields) > 0
+}
+
+// indexField returns the field a secondary index of db.table is keyed by,
+// or "" when the index is not on a single top-level field.
+func (s *RethinkDBServer) indexField(ctx context.Context, db, table, index string) (string, error) {
+	cursor, err := r.DB(db).Table(table).IndexStatus(index).Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return "", fmt.Errorf("failed to get index status: %w", err)
+	}
+	defer cursor.Close()
+
+	var status map[string]interface{}
+	if err := cursor.One(&status); err != nil {
+		return "", fmt.Errorf("failed to read index status: %w", err)
+	}
+	field, _ := indexKeyField(status)
+	return field, nil
+}
+
+// primaryKey returns the primary key field name of the given table.
+func (s *RethinkDBServer) primaryKey(ctx context.Context, db, table string) (string, error) {
+	cursor, err := r.DB(db).Table(table).Info().Field("primary_key").Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return "", fmt.Errorf("failed to get table info: %w", err)
+	}
+	defer cursor.Close()
+
+	var pk string
+	if err := cursor.One(&pk); err != nil {
+		return "", fmt.Errorf("failed to read primary key: %w", err)
+	}
+	return pk, nil
+}
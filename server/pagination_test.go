@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── pagination ─────────────────────────────────────────────────────────────
+
+func TestDecodeCursor_RejectsDifferentShape(t *testing.T) {
+	token, err := encodeCursor(pageCursor{Shape: queryShape("a"), Index: "id", Key: "1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := decodeCursor(token, queryShape("b")); err == nil {
+		t.Error("expected error for cursor from a different query")
+	}
+	c, err := decodeCursor(token, queryShape("a"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Key != "1" {
+		t.Errorf("expected key %q, got %v", "1", c.Key)
+	}
+}
+
+func TestDecodeCursor_Garbage_ReturnsError(t *testing.T) {
+	if _, err := decodeCursor("not a cursor!", queryShape("a")); err == nil {
+		t.Error("expected error for malformed cursor")
+	}
+}
+
+func TestNextCursor_IndexField(t *testing.T) {
+	doc := map[string]interface{}{"id": "1", "first": "Ada", "last": "Lovelace"}
+	if _, err := nextCursor(doc, queryShape("a"), "by_last", "last", "id"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := nextCursor(doc, queryShape("a"), "age", "age", "id"); err == nil {
+		t.Error("expected an error for a document without the index field")
+	}
+}
+
+func TestIndexKeyField(t *testing.T) {
+	renamed := map[string]interface{}{"index": "by_last", "query": `indexCreate('by_last', function(var1) { return var1("last"); })`}
+	if field, ok := indexKeyField(renamed); !ok || field != "last" {
+		t.Errorf("expected by_last to be keyed by last, got %q, %v", field, ok)
+	}
+	compound := map[string]interface{}{"index": "full_name", "query": `indexCreate('full_name', function(var1) { return [var1("last"), var1("first")]; })`}
+	if _, ok := indexKeyField(compound); ok {
+		t.Error("expected a compound index to have no key field")
+	}
+	nested := map[string]interface{}{"index": "city", "query": `indexCreate('city', function(var1) { return var1("address")("city"); })`}
+	if _, ok := indexKeyField(nested); ok {
+		t.Error("expected a nested path index to have no key field")
+	}
+}
+
+// collectQueryPages follows next_cursor until exhausted and returns every "id".
+func collectQueryPages(t *testing.T, input QueryTableInput) []interface{} {
+	t.Helper()
+	srv := newTestServer()
+	var ids []interface{}
+	for page := 0; page < 10; page++ {
+		_, output, err := srv.QueryTable(context.Background(), &mcp.CallToolRequest{}, input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, res := range output.Results {
+			ids = append(ids, res.(map[string]interface{})["id"])
+		}
+		if output.NextCursor == "" {
+			return ids
+		}
+		input.Cursor = output.NextCursor
+	}
+	t.Fatal("pagination did not terminate")
+	return nil
+}
+
+func TestQueryTable_PaginatesByPrimaryKey(t *testing.T) {
+	ids := collectQueryPages(t, QueryTableInput{Database: testDB, Table: testTable, Limit: 1})
+	if len(ids) != 3 {
+		t.Fatalf("expected 3 documents across pages, got %v", ids)
+	}
+	for i, want := range []string{"1", "2", "3"} {
+		if ids[i] != want {
+			t.Errorf("expected id %q at position %d, got %v", want, i, ids[i])
+		}
+	}
+}
+
+func TestQueryTable_PaginatesByOrderByField(t *testing.T) {
+	ids := collectQueryPages(t, QueryTableInput{Database: testDB, Table: testTable, OrderBy: "name", Limit: 2})
+	if len(ids) != 3 {
+		t.Fatalf("expected 3 documents across pages, got %v", ids)
+	}
+}
+
+func TestQueryTable_CursorFromOtherQuery_ReturnsError(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.QueryTable(context.Background(), &mcp.CallToolRequest{}, QueryTableInput{
+		Database: testDB, Table: testTable, Limit: 1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.NextCursor == "" {
+		t.Fatal("expected next_cursor")
+	}
+	_, _, err = srv.QueryTable(context.Background(), &mcp.CallToolRequest{}, QueryTableInput{
+		Database: testDB, Table: testTable, OrderBy: "name", Limit: 1, Cursor: output.NextCursor,
+	})
+	if err == nil {
+		t.Error("expected error for cursor issued to a different query")
+	}
+}
+
+func TestAdvancedQuery_BetweenCompoundIndexReturnsFirstPage(t *testing.T) {
+	table := r.DB(testDB).Table(testTable)
+	table.IndexCreateFunc("mcp_test_age_name", func(row r.Term) interface{} {
+		return []interface{}{row.Field("age"), row.Field("name")}
+	}).RunWrite(testSession)
+	defer table.IndexDrop("mcp_test_age_name").RunWrite(testSession)
+	table.IndexWait("mcp_test_age_name").Run(testSession)
+
+	srv := newTestServer()
+	_, output, err := srv.AdvancedQuery(context.Background(), &mcp.CallToolRequest{}, AdvancedQueryInput{
+		Database:  testDB,
+		Table:     testTable,
+		Operation: "between",
+		Index:     "mcp_test_age_name",
+		Limit:     2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Count != 2 || output.NextCursor != "" || output.Note == "" {
+		t.Errorf("expected the first page with a note and no next_cursor, got %d results, cursor %q and note %q", output.Count, output.NextCursor, output.Note)
+	}
+}
+
+func TestAdvancedQuery_BetweenPaginates(t *testing.T) {
+	srv := newTestServer()
+	input := AdvancedQueryInput{
+		Database:   testDB,
+		Table:      testTable,
+		Operation:  "between",
+		Index:      "age",
+		LowerBound: json.RawMessage(`25`),
+		UpperBound: json.RawMessage(`36`),
+		Limit:      2,
+	}
+	_, first, err := srv.AdvancedQuery(context.Background(), &mcp.CallToolRequest{}, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Count != 2 || first.NextCursor == "" {
+		t.Fatalf("expected a full first page with next_cursor, got %d results and cursor %q", first.Count, first.NextCursor)
+	}
+	input.Cursor = first.NextCursor
+	_, second, err := srv.AdvancedQuery(context.Background(), &mcp.CallToolRequest{}, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second.Count != 1 || second.NextCursor != "" {
+		t.Errorf("expected 1 final result without cursor, got %d results and cursor %q", second.Count, second.NextCursor)
+	}
+	if age := second.Results[0].(map[string]interface{})["age"]; age != float64(35) {
+		t.Errorf("expected last page to hold age 35, got %v", age)
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// Ways query_table can use an index instead of scanning the table.
+const (
+	// indexGetAll looks documents up with GetAll on an equality filter.
+	indexGetAll = "get_all"
+	// indexOrderBy orders the table by an index named after order_by.
+	indexOrderBy = "order_by"
+)
+
+// queryPlan is the index query_table reads through, if any.
+type queryPlan struct {
+	Index string
+	Usage string
+	// Value is the GetAll key when Usage is indexGetAll.
+	Value interface{}
+}
+
+// fieldIndexQuery matches the query RethinkDB reports for an index created
+// on a single top-level field, e.g.
+// indexCreate('age', function(var1) { return var1("age"); }).
+var fieldIndexQuery = regexp.MustCompile(`function\((\w+)\)\s*\{\s*return\s+(\w+)\("((?:[^"\\]|\\.)*)"\);\s*\}`)
+
+// compoundIndexQuery matches the query RethinkDB reports for a compound
+// index, e.g. indexCreate('status_age', function(var1) { return
+// [var1("status"), var1("age")]; }), capturing the array's elements.
+var compoundIndexQuery = regexp.MustCompile(`function\((\w+)\)\s*\{\s*return\s+(?:r\.expr\()?\[(.*)\]\)?;\s*\}`)
+
+// compoundIndexElement matches one top-level field at the start of a
+// compound index's array elements.
+var compoundIndexElement = regexp.MustCompile(`^\s*(\w+)\("((?:[^"\\]|\\.)*)"\)\s*(?:,|$)`)
+
+// fieldIndexes returns the ready, single-field secondary indexes of a table,
+// keyed by the field they index. Multi, geo, compound and function indexes
+// are left out because their keys are not the field value itself.
+func (s *RethinkDBServer) fieldIndexes(ctx context.Context, db, table string) (map[string]bool, error) {
+	cursor, err := r.DB(db).Table(table).IndexStatus().Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index status: %w", err)
+	}
+	defer cursor.Close()
+
+	var statuses []map[string]interface{}
+	if err := cursor.All(&statuses); err != nil {
+		return nil, fmt.Errorf("failed to read index status: %w", err)
+	}
+
+	indexes := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		if field, ok := indexedField(status); ok {
+			indexes[field] = true
+		}
+	}
+	return indexes, nil
+}
+
+// indexedField returns the field a ready index covers when the index is
+// named after a single field and keyed by its value.
+func indexedField(status map[string]interface{}) (string, bool) {
+	detail := indexDetail(status)
+	field, ok := indexKeyField(status)
+	if !ok || !detail.Ready || field != detail.Name {
+		return "", false
+	}
+	return field, true
+}
+
+// planQuery picks the index query_table reads through. An equality filter
+// on the primary key or an indexed field becomes a GetAll, preferring the
+// primary key since it matches at most one document; otherwise an order_by
+// on an indexed field orders by that index. The full filter is still
+// applied to the documents read, so the plan never changes the results.
+func planQuery(filter map[string]interface{}, orderBy, pk string, indexes map[string]bool) queryPlan {
+	fields := make([]string, 0, len(filter))
+	for field := range filter {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i] == pk && fields[j] != pk })
+
+	for _, field := range fields {
+		if field != pk && !indexes[field] {
+			continue
+		}
+		if value, ok := equalityValue(filter[field]); ok {
+			return queryPlan{Index: field, Usage: indexGetAll, Value: value}
+		}
+	}
+	if orderBy != "" && orderBy != pk && indexes[orderBy] {
+		return queryPlan{Index: orderBy, Usage: indexOrderBy}
+	}
+	return queryPlan{}
+}
+
+// equalityValue returns the value a filter entry requires a field to equal,
+// given as a plain value or {"$eq": value}. Only strings, numbers and
+// booleans qualify: secondary indexes do not store null, and a plain object
+// matches nested fields rather than the whole value.
+func equalityValue(value interface{}) (interface{}, bool) {
+	if ops, ok := value.(map[string]interface{}); ok {
+		eq, ok := ops["$eq"]
+		if !ok {
+			return nil, false
+		}
+		value = eq
+	}
+	switch value.(type) {
+	case string, float64, int, int64, bool:
+		return value, true
+	}
+	return nil, false
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ─── index planning ─────────────────────────────────────────────────────────
+
+func TestPlanQuery(t *testing.T) {
+	indexes := map[string]bool{"age": true, "status": true}
+	tests := []struct {
+		name    string
+		filter  map[string]interface{}
+		orderBy string
+		want    queryPlan
+	}{
+		{"no filter", nil, "", queryPlan{}},
+		{"equality on index", map[string]interface{}{"status": "active"}, "", queryPlan{Index: "status", Usage: indexGetAll, Value: "active"}},
+		{"$eq on index", map[string]interface{}{"age": map[string]interface{}{"$eq": 30.0}}, "", queryPlan{Index: "age", Usage: indexGetAll, Value: 30.0}},
+		{"primary key first", map[string]interface{}{"status": "active", "id": "1"}, "", queryPlan{Index: "id", Usage: indexGetAll, Value: "1"}},
+		{"range on index", map[string]interface{}{"age": map[string]interface{}{"$gt": 30.0}}, "", queryPlan{}},
+		{"null is not indexed", map[string]interface{}{"status": nil}, "", queryPlan{}},
+		{"unindexed field", map[string]interface{}{"name": "Alice"}, "", queryPlan{}},
+		{"order by index", map[string]interface{}{"name": "Alice"}, "age", queryPlan{Index: "age", Usage: indexOrderBy}},
+		{"equality wins over order", map[string]interface{}{"status": "active"}, "age", queryPlan{Index: "status", Usage: indexGetAll, Value: "active"}},
+		{"order by primary key", nil, "id", queryPlan{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := planQuery(tt.filter, tt.orderBy, "id", indexes); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIndexedField(t *testing.T) {
+	plain := map[string]interface{}{"index": "age", "ready": true, "query": `indexCreate('age', function(var1) { return var1("age"); })`}
+	if field, ok := indexedField(plain); !ok || field != "age" {
+		t.Errorf("expected a field index on age, got %q, %v", field, ok)
+	}
+	function := map[string]interface{}{"index": "name", "ready": true, "query": `indexCreate('name', function(var1) { return var1("name").downcase(); })`}
+	if _, ok := indexedField(function); ok {
+		t.Error("expected a function index to be skipped")
+	}
+	multi := map[string]interface{}{"index": "tags", "ready": true, "multi": true}
+	if _, ok := indexedField(multi); ok {
+		t.Error("expected a multi index to be skipped")
+	}
+	building := map[string]interface{}{"index": "age", "ready": false}
+	if _, ok := indexedField(building); ok {
+		t.Error("expected an index that is not ready to be skipped")
+	}
+}
+
+func TestQueryTable_UsesIndexForEquality(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.QueryTable(context.Background(), &mcp.CallToolRequest{}, QueryTableInput{
+		Database: testDB,
+		Table:    testTable,
+		Filter:   map[string]any{"status": "active"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Index != "status" || output.IndexUsage != indexGetAll {
+		t.Errorf("expected get_all on status, got %q %q", output.Index, output.IndexUsage)
+	}
+	if output.Count != 2 {
+		t.Errorf("expected 2 results, got %d", output.Count)
+	}
+}
+
+func TestQueryTable_UsesIndexForOrder(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.QueryTable(context.Background(), &mcp.CallToolRequest{}, QueryTableInput{
+		Database: testDB,
+		Table:    testTable,
+		OrderBy:  "age",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Index != "age" || output.IndexUsage != indexOrderBy {
+		t.Errorf("expected order_by on age, got %q %q", output.Index, output.IndexUsage)
+	}
+	if output.Count != 3 || output.Results[0].(map[string]interface{})["name"] != "Bob" {
+		t.Errorf("expected Bob first, got %v", output.Results)
+	}
+}
+
+func TestQueryTable_ScansWithoutIndex(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.QueryTable(context.Background(), &mcp.CallToolRequest{}, QueryTableInput{
+		Database: testDB,
+		Table:    testTable,
+		Filter:   map[string]any{"name": "Alice"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Index != "" || output.Count != 1 {
+		t.Errorf("expected a table scan with 1 result, got index %q and %d results", output.Index, output.Count)
+	}
+}
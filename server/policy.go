@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+)
+
+// ErrAccessDenied is returned when a tool call references a database or
+// table that the configured policy does not allow.
+var ErrAccessDenied = errors.New("access denied by policy")
+
+// Policy restricts which databases and tables the tools may touch. Database
+// patterns match database names; table patterns match "database.table".
+// Patterns use path.Match glob syntax, e.g. "rethinkdb", "app_*" or
+// "*.users_pii". A name is allowed when it matches no deny pattern and
+// either the allow list is empty or it matches at least one allow pattern.
+type Policy struct {
+	AllowDatabases []string `json:"allow_databases,omitempty"`
+	DenyDatabases  []string `json:"deny_databases,omitempty"`
+	AllowTables    []string `json:"allow_tables,omitempty"`
+	DenyTables     []string `json:"deny_tables,omitempty"`
+}
+
+// LoadPolicy reads a JSON-encoded Policy from the file at filename.
+func LoadPolicy(filename string) (*Policy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file: %w", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Validate reports whether every pattern in the policy is well formed.
+func (p *Policy) Validate() error {
+	for _, patterns := range [][]string{p.AllowDatabases, p.DenyDatabases, p.AllowTables, p.DenyTables} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid policy pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// DatabaseAllowed reports whether db may be accessed. A nil policy allows
+// everything.
+func (p *Policy) DatabaseAllowed(db string) bool {
+	if p == nil {
+		return true
+	}
+	return allowedByPatterns(db, p.AllowDatabases, p.DenyDatabases)
+}
+
+// TableAllowed reports whether table in db may be accessed. The database
+// itself must also be allowed.
+func (p *Policy) TableAllowed(db, table string) bool {
+	if p == nil {
+		return true
+	}
+	if !p.DatabaseAllowed(db) {
+		return false
+	}
+	return allowedByPatterns(db+"."+table, p.AllowTables, p.DenyTables)
+}
+
+func allowedByPatterns(name string, allow, deny []string) bool {
+	if matchesAny(name, deny) {
+		return false
+	}
+	return len(allow) == 0 || matchesAny(name, allow)
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDatabase returns an error wrapping ErrAccessDenied if db is outside
+// the configured policy.
+func (s *RethinkDBServer) checkDatabase(db string) error {
+	if !s.policy.DatabaseAllowed(db) {
+		return fmt.Errorf("%w: database %q", ErrAccessDenied, db)
+	}
+	return nil
+}
+
+// checkTables returns an error wrapping ErrAccessDenied if any of the given
+// tables in db is outside the configured policy or is the audit table.
+// Reading the audit table directly would bypass the access policy
+// list_audit_log applies to its entries, so no other tool may touch it.
+func (s *RethinkDBServer) checkTables(db string, tables ...string) error {
+	if err := s.checkDatabase(db); err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if !s.policy.TableAllowed(db, table) {
+			return fmt.Errorf("%w: table %q.%q", ErrAccessDenied, db, table)
+		}
+		if s.isAuditTable(db, table) {
+			return fmt.Errorf("%w: the audit log table %s.%s can only be read with list_audit_log", ErrAccessDenied, db, table)
+		}
+	}
+	return nil
+}
+
+// checkReservedTables returns an error wrapping ErrAccessDenied if any of
+// the given tables in db is the schema table, which holds the schemas
+// write_data validates against, or the audit table, which is append-only.
+// No tool may change either.
+func (s *RethinkDBServer) checkReservedTables(db string, tables ...string) error {
+	for _, table := range tables {
+		switch {
+		case s.isSchemaTable(db, table):
+			return fmt.Errorf("%w: %s.%s holds the write_data schemas and cannot be changed through this server", ErrAccessDenied, db, table)
+		case s.isAuditTable(db, table):
+			return fmt.Errorf("%w: the audit log table %s.%s is append-only", ErrAccessDenied, db, table)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ─── access policy ──────────────────────────────────────────────────────────
+
+func TestPolicy_NilAllowsEverything(t *testing.T) {
+	var policy *Policy
+	if !policy.DatabaseAllowed("rethinkdb") {
+		t.Error("expected nil policy to allow database")
+	}
+	if !policy.TableAllowed("rethinkdb", "users") {
+		t.Error("expected nil policy to allow table")
+	}
+}
+
+func TestPolicy_GlobPatterns(t *testing.T) {
+	policy := &Policy{
+		DenyDatabases: []string{"rethinkdb"},
+		AllowTables:   []string{"app_*.*"},
+		DenyTables:    []string{"*.users_pii"},
+	}
+	cases := []struct {
+		db, table string
+		want      bool
+	}{
+		{"app_main", "orders", true},
+		{"app_main", "users_pii", false},
+		{"rethinkdb", "table_config", false},
+		{"other", "orders", false},
+	}
+	for _, c := range cases {
+		if got := policy.TableAllowed(c.db, c.table); got != c.want {
+			t.Errorf("TableAllowed(%q, %q) = %v, want %v", c.db, c.table, got, c.want)
+		}
+	}
+	if policy.DatabaseAllowed("rethinkdb") {
+		t.Error("expected rethinkdb database to be denied")
+	}
+}
+
+func TestLoadPolicy_InvalidPattern_ReturnsError(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(filename, []byte(`{"deny_databases": ["["]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicy(filename); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
+
+func TestPolicy_ListDatabasesFiltered(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithPolicy(&Policy{DenyDatabases: []string{"rethinkdb"}}))
+	_, output, err := srv.ListDatabases(context.Background(), &mcp.CallToolRequest{}, EmptyInput{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, db := range output.Databases {
+		if db == "rethinkdb" {
+			t.Error("expected rethinkdb to be filtered out")
+		}
+	}
+}
+
+func TestPolicy_ListTablesFiltered(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithPolicy(&Policy{DenyTables: []string{testDB + "." + testJoinTable}}))
+	_, output, err := srv.ListTables(context.Background(), &mcp.CallToolRequest{}, ListTablesInput{Database: testDB})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tbl := range output.Tables {
+		if tbl == testJoinTable {
+			t.Errorf("expected %q to be filtered out", testJoinTable)
+		}
+	}
+}
+
+func TestPolicy_DeniedTableRejected(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithPolicy(&Policy{DenyTables: []string{"*." + testJoinTable}}))
+	ctx := context.Background()
+
+	_, _, err := srv.QueryTable(ctx, &mcp.CallToolRequest{}, QueryTableInput{Database: testDB, Table: testJoinTable})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("query_table: expected ErrAccessDenied, got %v", err)
+	}
+
+	_, _, err = srv.WriteData(ctx, &mcp.CallToolRequest{}, WriteDataInput{
+		Database: testDB,
+		Table:    testJoinTable,
+		Data:     json.RawMessage(`{"id": "denied"}`),
+	})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("write_data: expected ErrAccessDenied, got %v", err)
+	}
+
+	_, _, err = srv.AdvancedQuery(ctx, &mcp.CallToolRequest{}, AdvancedQueryInput{
+		Database:  testDB,
+		Table:     testTable,
+		Operation: "eq_join",
+		JoinField: "id",
+		JoinTable: testJoinTable,
+	})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("advanced_query: expected ErrAccessDenied for join_table, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"strings"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ProfileNode is one step of a query profile as reported by RethinkDB.
+// Steps that run once per document are sampled and report a mean duration
+// and a sample count instead of a duration.
+type ProfileNode struct {
+	Description    string          `json:"description"`
+	DurationMs     float64         `json:"duration_ms,omitempty"`
+	MeanDurationMs float64         `json:"mean_duration_ms,omitempty"`
+	Samples        int             `json:"samples,omitempty"`
+	SubTasks       []ProfileNode   `json:"sub_tasks,omitempty"`
+	ParallelTasks  [][]ProfileNode `json:"parallel_tasks,omitempty"`
+}
+
+// ProfileSummary condenses a profile tree into the figures most useful for
+// spotting slow queries.
+type ProfileSummary struct {
+	// TotalMs is the server-side time of the query.
+	TotalMs float64 `json:"total_ms"`
+	// IndexUsed reports whether any step read through a secondary index or
+	// looked documents up by key. A query that scans the whole table shows
+	// only range scans on the primary index.
+	IndexUsed bool `json:"index_used"`
+	// IndexReads lists the profile steps that read through an index.
+	IndexReads []string `json:"index_reads,omitempty"`
+	// DocumentsRead estimates the documents examined from the largest sample
+	// count of a per-document step. It is 0 when the query has no such step.
+	DocumentsRead int `json:"documents_read"`
+}
+
+// QueryProfile is the profile returned for a query run with profile: true.
+type QueryProfile struct {
+	Summary ProfileSummary `json:"summary"`
+	Tree    []ProfileNode  `json:"tree"`
+}
+
+// parseProfile converts the raw profile returned by the driver. It returns
+// nil when raw is not a profile.
+func parseProfile(raw interface{}) *QueryProfile {
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil
+	}
+	profile := &QueryProfile{Tree: parseProfileNodes(items)}
+	for _, node := range profile.Tree {
+		profile.Summary.TotalMs += node.DurationMs
+	}
+	summarizeProfile(profile.Tree, &profile.Summary)
+	return profile
+}
+
+// profileOf returns the parsed profile of cursor when profiling was
+// requested.
+func profileOf(cursor *r.Cursor, requested bool) *QueryProfile {
+	if !requested {
+		return nil
+	}
+	return parseProfile(cursor.Profile())
+}
+
+func parseProfileNodes(items []interface{}) []ProfileNode {
+	nodes := make([]ProfileNode, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		node := ProfileNode{}
+		node.Description, _ = m["description"].(string)
+		node.DurationMs, _ = m["duration(ms)"].(float64)
+		node.MeanDurationMs, _ = m["mean_duration(ms)"].(float64)
+		if n, ok := m["n_samples"].(float64); ok {
+			node.Samples = int(n)
+		}
+		if sub, ok := m["sub_tasks"].([]interface{}); ok {
+			node.SubTasks = parseProfileNodes(sub)
+		}
+		if parallel, ok := m["parallel_tasks"].([]interface{}); ok {
+			for _, branch := range parallel {
+				if tasks, ok := branch.([]interface{}); ok {
+					node.ParallelTasks = append(node.ParallelTasks, parseProfileNodes(tasks))
+				}
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func summarizeProfile(nodes []ProfileNode, summary *ProfileSummary) {
+	for _, node := range nodes {
+		desc := strings.ToLower(node.Description)
+		if strings.Contains(desc, "index") || strings.Contains(desc, "get_all") {
+			summary.IndexReads = appendUnique(summary.IndexReads, node.Description)
+		}
+		if strings.Contains(desc, "secondary index") || strings.Contains(desc, "get_all") {
+			summary.IndexUsed = true
+		}
+		if node.Samples > summary.DocumentsRead {
+			summary.DocumentsRead = node.Samples
+		}
+		summarizeProfile(node.SubTasks, summary)
+		for _, branch := range node.ParallelTasks {
+			summarizeProfile(branch, summary)
+		}
+	}
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, item := range list {
+		if item == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ─── query profiling ────────────────────────────────────────────────────────
+
+func TestParseProfile(t *testing.T) {
+	var raw interface{}
+	err := json.Unmarshal([]byte(`[
+		{"description": "Evaluating limit.", "duration(ms)": 1.5, "sub_tasks": [
+			{"description": "Perform read.", "duration(ms)": 1.2, "sub_tasks": [], "parallel_tasks": [[
+				{"description": "Perform read on shard.", "duration(ms)": 0.8, "sub_tasks": [
+					{"description": "Do range scan on secondary index.", "duration(ms)": 0.5, "sub_tasks": []}
+				]}
+			]]},
+			{"description": "Evaluating filter.", "mean_duration(ms)": 0.01, "n_samples": 42}
+		]},
+		{"description": "Evaluating datum.", "duration(ms)": 0.5, "sub_tasks": []}
+	]`), &raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile := parseProfile(raw)
+	if profile == nil {
+		t.Fatal("expected a profile")
+	}
+	if profile.Summary.TotalMs != 2.0 {
+		t.Errorf("expected total 2ms, got %v", profile.Summary.TotalMs)
+	}
+	if !profile.Summary.IndexUsed {
+		t.Error("expected the secondary index read to be detected")
+	}
+	if len(profile.Summary.IndexReads) != 1 || profile.Summary.IndexReads[0] != "Do range scan on secondary index." {
+		t.Errorf("unexpected index reads: %v", profile.Summary.IndexReads)
+	}
+	if profile.Summary.DocumentsRead != 42 {
+		t.Errorf("expected 42 documents read, got %d", profile.Summary.DocumentsRead)
+	}
+	shard := profile.Tree[0].SubTasks[0].ParallelTasks[0][0]
+	if shard.Description != "Perform read on shard." || len(shard.SubTasks) != 1 {
+		t.Errorf("unexpected parallel task: %+v", shard)
+	}
+}
+
+func TestParseProfile_NotAProfile(t *testing.T) {
+	if profile := parseProfile(nil); profile != nil {
+		t.Errorf("expected nil, got %+v", profile)
+	}
+}
+
+func TestQueryTable_Profile(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.QueryTable(context.Background(), &mcp.CallToolRequest{}, QueryTableInput{
+		Database: testDB,
+		Table:    testTable,
+		Filter:   map[string]any{"status": "active"},
+		Profile:  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Count != 2 {
+		t.Errorf("expected 2 results, got %d", output.Count)
+	}
+	if output.Profile == nil || len(output.Profile.Tree) == 0 {
+		t.Fatal("expected a profile tree")
+	}
+	if output.Profile.Summary.TotalMs <= 0 {
+		t.Errorf("expected a positive total time, got %v", output.Profile.Summary.TotalMs)
+	}
+}
+
+func TestQueryTable_NoProfileByDefault(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.QueryTable(context.Background(), &mcp.CallToolRequest{}, QueryTableInput{
+		Database: testDB,
+		Table:    testTable,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Profile != nil {
+		t.Error("expected no profile unless requested")
+	}
+}
+
+func TestAggregate_Profile(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.Aggregate(context.Background(), &mcp.CallToolRequest{}, AggregateInput{
+		Database:  testDB,
+		Table:     testTable,
+		Operation: "count",
+		Profile:   true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Profile == nil {
+		t.Fatal("expected a profile")
+	}
+}
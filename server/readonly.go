@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+)
+
+// writeTermTypes are the ReQL term types that mutate data, schema or cluster
+// configuration. None of them may appear in a query run in read-only mode.
+var writeTermTypes = map[p.Term_TermType]bool{
+	p.Term_INSERT:         true,
+	p.Term_UPDATE:         true,
+	p.Term_REPLACE:        true,
+	p.Term_DELETE:         true,
+	p.Term_DB_CREATE:      true,
+	p.Term_DB_DROP:        true,
+	p.Term_TABLE_CREATE:   true,
+	p.Term_TABLE_DROP:     true,
+	p.Term_INDEX_CREATE:   true,
+	p.Term_INDEX_DROP:     true,
+	p.Term_INDEX_RENAME:   true,
+	p.Term_SYNC:           true,
+	p.Term_RECONFIGURE:    true,
+	p.Term_REBALANCE:      true,
+	p.Term_GRANT:          true,
+	p.Term_SET_WRITE_HOOK: true,
+}
+
+// ErrReadOnly is returned when a mutating operation is attempted while the
+// server is running in read-only mode.
+var ErrReadOnly = errors.New("server is running in read-only mode")
+
+// checkReadOnly returns an error wrapping ErrReadOnly if the server is in
+// read-only mode and term contains any write term.
+func (s *RethinkDBServer) checkReadOnly(term r.Term) error {
+	if !s.readOnly {
+		return nil
+	}
+	found, err := findWriteTerm(term)
+	if err != nil {
+		return fmt.Errorf("failed to inspect query: %w", err)
+	}
+	if found != nil {
+		return fmt.Errorf("%w: query contains write term %s", ErrReadOnly, found)
+	}
+	return nil
+}
+
+// findWriteTerm walks the wire representation of term and returns the first
+// write term type it contains, or nil if the query is read-only.
+func findWriteTerm(term r.Term) (*p.Term_TermType, error) {
+	built, err := term.Build()
+	if err != nil {
+		return nil, err
+	}
+	return findWriteTermIn(built), nil
+}
+
+// findWriteTermIn inspects a built term. Terms are encoded as
+// [type, [args...], {optargs}], objects as maps and datums as plain values.
+// Raw queries, such as those built by run_reql, are decoded first.
+func findWriteTermIn(v interface{}) *p.Term_TermType {
+	switch t := v.(type) {
+	case *json.RawMessage:
+		var decoded interface{}
+		if err := json.Unmarshal(*t, &decoded); err != nil {
+			return nil
+		}
+		return findWriteTermIn(decoded)
+	case []interface{}:
+		if len(t) == 0 {
+			return nil
+		}
+		var termType p.Term_TermType
+		switch typ := t[0].(type) {
+		case int:
+			termType = p.Term_TermType(typ)
+		case float64:
+			termType = p.Term_TermType(typ)
+		default:
+			return nil
+		}
+		if writeTermTypes[termType] {
+			return &termType
+		}
+		for _, rest := range t[1:] {
+			switch rest := rest.(type) {
+			case []interface{}:
+				for _, arg := range rest {
+					if found := findWriteTermIn(arg); found != nil {
+						return found
+					}
+				}
+			case map[string]interface{}:
+				if found := findWriteTermIn(rest); found != nil {
+					return found
+				}
+			}
+		}
+	case map[string]interface{}:
+		for _, val := range t {
+			if found := findWriteTermIn(val); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── read-only mode ─────────────────────────────────────────────────────────
+
+func TestFindWriteTerm_ReadQueries(t *testing.T) {
+	queries := []r.Term{
+		r.DB(testDB).Table(testTable),
+		r.DB(testDB).Table(testTable).Filter(map[string]interface{}{"status": "active"}).Limit(10),
+		r.DB(testDB).Table(testTable).Filter(func(row r.Term) r.Term {
+			return row.Field("tags").Contains("a")
+		}),
+		r.Expr(5).Add(3),
+		r.DB(testDB).Table(testTable).Group("status").Count().Ungroup(),
+	}
+	for _, q := range queries {
+		found, err := findWriteTerm(q)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", q, err)
+		}
+		if found != nil {
+			t.Errorf("expected no write term in %s, found %s", q, found)
+		}
+	}
+}
+
+func TestFindWriteTerm_WriteQueries(t *testing.T) {
+	queries := []r.Term{
+		r.DB(testDB).Table(testTable).Insert(map[string]interface{}{"id": "x"}),
+		r.DB(testDB).Table(testTable).Filter(map[string]interface{}{"status": "active"}).Delete(),
+		r.DB(testDB).Table(testTable).Get("1").Update(map[string]interface{}{"age": 1}),
+		r.DB(testDB).Table(testTable).Map(func(row r.Term) r.Term {
+			return r.DB(testDB).Table(testJoinTable).Insert(row)
+		}),
+		r.DBCreate("other"),
+		r.DB(testDB).TableDrop(testTable),
+	}
+	for _, q := range queries {
+		found, err := findWriteTerm(q)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", q, err)
+		}
+		if found == nil {
+			t.Errorf("expected write term in %s", q)
+		}
+	}
+}
+
+func TestReadOnly_WriteDataRejected(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithReadOnly(true))
+	input := WriteDataInput{
+		Database: testDB,
+		Table:    testTable,
+		Data:     json.RawMessage(`{"id": "readonly-1", "name": "Nope"}`),
+	}
+	_, _, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, input)
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestReadOnly_QueriesStillWork(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithReadOnly(true))
+	_, output, err := srv.QueryTable(context.Background(), &mcp.CallToolRequest{}, QueryTableInput{
+		Database: testDB,
+		Table:    testTable,
+		Filter:   map[string]interface{}{"status": "active"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Count != 2 {
+		t.Errorf("expected 2 results, got %d", output.Count)
+	}
+}
@@ -0,0 +1,1025 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+	p "gopkg.in/rethinkdb/rethinkdb-go.v6/ql2"
+)
+
+// ReQLPermissions enables ReQL features that run_reql rejects by default.
+type ReQLPermissions struct {
+	// JavaScript allows r.js, which runs arbitrary code on the server.
+	JavaScript bool
+	// HTTP allows r.http, which makes outbound requests from the server.
+	HTTP bool
+	// Writes allows insert, update, replace and delete. Read-only mode still
+	// rejects them.
+	Writes bool
+}
+
+// reqlGate names the permission a whitelisted method requires, if any.
+type reqlGate int
+
+const (
+	gateNone reqlGate = iota
+	gateWrite
+	gateJavaScript
+	gateHTTP
+)
+
+// reqlMethod describes a whitelisted ReQL method or r.* function.
+type reqlMethod struct {
+	term p.Term_TermType
+	// minArgs is the number of positional arguments the method requires; a
+	// trailing object literal beyond them is sent as optional arguments when
+	// all of its keys are listed in optArgs.
+	minArgs int
+	optArgs []string
+	gate    reqlGate
+}
+
+// reqlRootFunctions are the functions callable as r.<name>(...).
+var reqlRootFunctions = map[string]reqlMethod{
+	"db":        {term: p.Term_DB, minArgs: 1},
+	"expr":      {term: p.Term_DATUM, minArgs: 1},
+	"and":       {term: p.Term_AND},
+	"or":        {term: p.Term_OR},
+	"not":       {term: p.Term_NOT, minArgs: 1},
+	"branch":    {term: p.Term_BRANCH, minArgs: 3},
+	"now":       {term: p.Term_NOW},
+	"asc":       {term: p.Term_ASC, minArgs: 1},
+	"desc":      {term: p.Term_DESC, minArgs: 1},
+	"literal":   {term: p.Term_LITERAL},
+	"object":    {term: p.Term_OBJECT},
+	"range":     {term: p.Term_RANGE},
+	"uuid":      {term: p.Term_UUID},
+	"epochTime": {term: p.Term_EPOCH_TIME, minArgs: 1},
+	"ISO8601":   {term: p.Term_ISO8601, minArgs: 1, optArgs: []string{"defaultTimezone"}},
+	"time":      {term: p.Term_TIME, minArgs: 3},
+	"point":     {term: p.Term_POINT, minArgs: 2},
+	"line":      {term: p.Term_LINE, minArgs: 2},
+	"polygon":   {term: p.Term_POLYGON, minArgs: 3},
+	"circle":    {term: p.Term_CIRCLE, minArgs: 2, optArgs: []string{"numVertices", "geoSystem", "unit", "fill"}},
+	"geojson":   {term: p.Term_GEOJSON, minArgs: 1},
+	"distance":  {term: p.Term_DISTANCE, minArgs: 2, optArgs: []string{"geoSystem", "unit"}},
+	"do":        {term: p.Term_FUNCALL, minArgs: 1},
+	"js":        {term: p.Term_JAVASCRIPT, minArgs: 1, optArgs: []string{"timeout"}, gate: gateJavaScript},
+	"http":      {term: p.Term_HTTP, minArgs: 1, optArgs: []string{"data", "timeout", "method", "params", "header", "attempts", "redirects", "verify", "resultFormat", "auth", "page", "pageLimit"}, gate: gateHTTP},
+}
+
+// reqlMethods are the methods callable on a term as <term>.<name>(...).
+var reqlMethods = map[string]reqlMethod{
+	// Databases and tables. tableList is left out: its result would list
+	// tables the access policy hides; list_tables filters them instead.
+	"table":       {term: p.Term_TABLE, minArgs: 1, optArgs: []string{"readMode", "identifierFormat"}},
+	"info":        {term: p.Term_INFO},
+	"indexList":   {term: p.Term_INDEX_LIST},
+	"indexStatus": {term: p.Term_INDEX_STATUS},
+
+	// Selection
+	"get":             {term: p.Term_GET, minArgs: 1},
+	"getAll":          {term: p.Term_GET_ALL, minArgs: 1, optArgs: []string{"index"}},
+	"between":         {term: p.Term_BETWEEN, minArgs: 2, optArgs: []string{"index", "leftBound", "rightBound"}},
+	"filter":          {term: p.Term_FILTER, minArgs: 1, optArgs: []string{"default"}},
+	"getIntersecting": {term: p.Term_GET_INTERSECTING, minArgs: 1, optArgs: []string{"index"}},
+	"getNearest":      {term: p.Term_GET_NEAREST, minArgs: 1, optArgs: []string{"index", "maxResults", "maxDist", "unit", "geoSystem"}},
+
+	// Joins
+	"innerJoin": {term: p.Term_INNER_JOIN, minArgs: 2},
+	"outerJoin": {term: p.Term_OUTER_JOIN, minArgs: 2},
+	"eqJoin":    {term: p.Term_EQ_JOIN, minArgs: 2, optArgs: []string{"index", "ordered"}},
+	"zip":       {term: p.Term_ZIP},
+
+	// Transformations
+	"map":        {term: p.Term_MAP, minArgs: 1},
+	"concatMap":  {term: p.Term_CONCAT_MAP, minArgs: 1},
+	"withFields": {term: p.Term_WITH_FIELDS, minArgs: 1},
+	"orderBy":    {term: p.Term_ORDER_BY, optArgs: []string{"index"}},
+	"skip":       {term: p.Term_SKIP, minArgs: 1},
+	"limit":      {term: p.Term_LIMIT, minArgs: 1},
+	"slice":      {term: p.Term_SLICE, minArgs: 1, optArgs: []string{"leftBound", "rightBound"}},
+	"nth":        {term: p.Term_NTH, minArgs: 1},
+	"offsetsOf":  {term: p.Term_OFFSETS_OF, minArgs: 1},
+	"isEmpty":    {term: p.Term_IS_EMPTY},
+	"union":      {term: p.Term_UNION, minArgs: 1, optArgs: []string{"interleave"}},
+	"sample":     {term: p.Term_SAMPLE, minArgs: 1},
+
+	// Aggregation
+	"group":    {term: p.Term_GROUP, optArgs: []string{"index", "multi"}},
+	"ungroup":  {term: p.Term_UNGROUP},
+	"reduce":   {term: p.Term_REDUCE, minArgs: 1},
+	"count":    {term: p.Term_COUNT},
+	"sum":      {term: p.Term_SUM},
+	"avg":      {term: p.Term_AVG},
+	"min":      {term: p.Term_MIN, optArgs: []string{"index"}},
+	"max":      {term: p.Term_MAX, optArgs: []string{"index"}},
+	"distinct": {term: p.Term_DISTINCT, optArgs: []string{"index"}},
+	"contains": {term: p.Term_CONTAINS},
+
+	// Document manipulation
+	"pluck":           {term: p.Term_PLUCK},
+	"without":         {term: p.Term_WITHOUT},
+	"merge":           {term: p.Term_MERGE, minArgs: 1},
+	"append":          {term: p.Term_APPEND, minArgs: 1},
+	"prepend":         {term: p.Term_PREPEND, minArgs: 1},
+	"difference":      {term: p.Term_DIFFERENCE, minArgs: 1},
+	"setInsert":       {term: p.Term_SET_INSERT, minArgs: 1},
+	"setUnion":        {term: p.Term_SET_UNION, minArgs: 1},
+	"setIntersection": {term: p.Term_SET_INTERSECTION, minArgs: 1},
+	"setDifference":   {term: p.Term_SET_DIFFERENCE, minArgs: 1},
+	"getField":        {term: p.Term_GET_FIELD, minArgs: 1},
+	"hasFields":       {term: p.Term_HAS_FIELDS},
+	"insertAt":        {term: p.Term_INSERT_AT, minArgs: 2},
+	"spliceAt":        {term: p.Term_SPLICE_AT, minArgs: 2},
+	"deleteAt":        {term: p.Term_DELETE_AT, minArgs: 1},
+	"changeAt":        {term: p.Term_CHANGE_AT, minArgs: 2},
+	"keys":            {term: p.Term_KEYS},
+	"values":          {term: p.Term_VALUES},
+
+	// Strings
+	"match":    {term: p.Term_MATCH, minArgs: 1},
+	"split":    {term: p.Term_SPLIT},
+	"upcase":   {term: p.Term_UPCASE},
+	"downcase": {term: p.Term_DOWNCASE},
+
+	// Math and logic
+	"add":   {term: p.Term_ADD, minArgs: 1},
+	"sub":   {term: p.Term_SUB, minArgs: 1},
+	"mul":   {term: p.Term_MUL, minArgs: 1},
+	"div":   {term: p.Term_DIV, minArgs: 1},
+	"mod":   {term: p.Term_MOD, minArgs: 1},
+	"and":   {term: p.Term_AND},
+	"or":    {term: p.Term_OR},
+	"eq":    {term: p.Term_EQ, minArgs: 1},
+	"ne":    {term: p.Term_NE, minArgs: 1},
+	"gt":    {term: p.Term_GT, minArgs: 1},
+	"ge":    {term: p.Term_GE, minArgs: 1},
+	"lt":    {term: p.Term_LT, minArgs: 1},
+	"le":    {term: p.Term_LE, minArgs: 1},
+	"not":   {term: p.Term_NOT},
+	"floor": {term: p.Term_FLOOR},
+	"ceil":  {term: p.Term_CEIL},
+	"round": {term: p.Term_ROUND},
+
+	// Dates and times
+	"inTimezone":  {term: p.Term_IN_TIMEZONE, minArgs: 1},
+	"timezone":    {term: p.Term_TIMEZONE},
+	"during":      {term: p.Term_DURING, minArgs: 2, optArgs: []string{"leftBound", "rightBound"}},
+	"date":        {term: p.Term_DATE},
+	"timeOfDay":   {term: p.Term_TIME_OF_DAY},
+	"year":        {term: p.Term_YEAR},
+	"month":       {term: p.Term_MONTH},
+	"day":         {term: p.Term_DAY},
+	"dayOfWeek":   {term: p.Term_DAY_OF_WEEK},
+	"dayOfYear":   {term: p.Term_DAY_OF_YEAR},
+	"hours":       {term: p.Term_HOURS},
+	"minutes":     {term: p.Term_MINUTES},
+	"seconds":     {term: p.Term_SECONDS},
+	"toISO8601":   {term: p.Term_TO_ISO8601},
+	"toEpochTime": {term: p.Term_TO_EPOCH_TIME},
+
+	// Control structures
+	"do":           {term: p.Term_FUNCALL, minArgs: 1},
+	"branch":       {term: p.Term_BRANCH, minArgs: 2},
+	"default":      {term: p.Term_DEFAULT, minArgs: 1},
+	"coerceTo":     {term: p.Term_COERCE_TO, minArgs: 1},
+	"typeOf":       {term: p.Term_TYPE_OF},
+	"toJSON":       {term: p.Term_TO_JSON_STRING},
+	"toJsonString": {term: p.Term_TO_JSON_STRING},
+
+	// Geospatial
+	"distance":   {term: p.Term_DISTANCE, minArgs: 1, optArgs: []string{"geoSystem", "unit"}},
+	"fill":       {term: p.Term_FILL},
+	"toGeojson":  {term: p.Term_TO_GEOJSON},
+	"includes":   {term: p.Term_INCLUDES, minArgs: 1},
+	"intersects": {term: p.Term_INTERSECTS, minArgs: 1},
+
+	// Writes
+	"insert":  {term: p.Term_INSERT, minArgs: 1, optArgs: []string{"conflict", "durability", "returnChanges"}, gate: gateWrite},
+	"update":  {term: p.Term_UPDATE, minArgs: 1, optArgs: []string{"durability", "returnChanges", "nonAtomic"}, gate: gateWrite},
+	"replace": {term: p.Term_REPLACE, minArgs: 1, optArgs: []string{"durability", "returnChanges", "nonAtomic"}, gate: gateWrite},
+	"delete":  {term: p.Term_DELETE, optArgs: []string{"durability", "returnChanges"}, gate: gateWrite},
+}
+
+// reqlTableRef is a table referenced by a parsed query.
+type reqlTableRef struct {
+	Database string
+	Table    string
+}
+
+// parsedReQL is the result of parsing a ReQL expression.
+type parsedReQL struct {
+	Term      r.Term
+	Databases []string
+	Tables    []reqlTableRef
+}
+
+// parseReQL parses a subset of the ReQL JavaScript syntax, for example
+//
+//	r.db('app').table('users').filter(r.row('age').gt(30)).pluck('name')
+//
+// into a term. Only whitelisted methods are accepted; r.js, r.http and write
+// methods additionally require the matching permission. Tables must be
+// reached through r.db('<name>') with literal names so access can be checked
+// against the policy before the query runs.
+func parseReQL(src string, perms ReQLPermissions) (*parsedReQL, error) {
+	ps, v, err := parseReQLExpr(src, perms)
+	if err != nil {
+		return nil, err
+	}
+	wire, err := json.Marshal(v.wire)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+	return &parsedReQL{
+		Term:      r.RawQuery(wire),
+		Databases: ps.databases,
+		Tables:    ps.tables,
+	}, nil
+}
+
+// parseReQLFunction parses a function of one document, written either with
+// r.row, e.g. r.row('email').downcase(), or as a function literal, e.g.
+// function (doc) { return [doc('last'), doc('first')]; }. It is used for
+// index functions, so it may not reference databases or tables, and r.js,
+// r.http and writes are always rejected.
+func parseReQLFunction(src string) (r.Term, error) {
+	ps, v, err := parseReQLExpr(src, ReQLPermissions{})
+	if err != nil {
+		return r.Term{}, err
+	}
+	if len(ps.databases) > 0 || len(ps.tables) > 0 {
+		return r.Term{}, fmt.Errorf("function must not reference databases or tables")
+	}
+	switch {
+	case v.implicit:
+		v = ps.wrapImplicit(v, false)
+	case funcArity(v.wire) != 1:
+		return r.Term{}, fmt.Errorf("expression must be a function of one argument or use r.row")
+	}
+	wire, err := json.Marshal(v.wire)
+	if err != nil {
+		return r.Term{}, fmt.Errorf("failed to encode function: %w", err)
+	}
+	return r.RawQuery(wire), nil
+}
+
+// parseReQLExpr parses src as a single expression, optionally followed by a
+// semicolon.
+func parseReQLExpr(src string, perms ReQLPermissions) (*reqlParser, reqlValue, error) {
+	toks, err := lexReQL(src)
+	if err != nil {
+		return nil, reqlValue{}, err
+	}
+	ps := &reqlParser{toks: toks, perms: perms, scope: map[string]int{}}
+	v, err := ps.parseExpr()
+	if err != nil {
+		return nil, reqlValue{}, err
+	}
+	if tok := ps.peek(); tok.kind != tokEOF {
+		if tok.kind == tokPunct && tok.text == ";" && ps.toks[ps.pos+1].kind == tokEOF {
+			ps.pos++
+		} else {
+			return nil, reqlValue{}, ps.errorf(tok, "unexpected %q", tok.text)
+		}
+	}
+	if v.root {
+		return nil, reqlValue{}, fmt.Errorf("query must not be the bare r namespace")
+	}
+	return ps, v, nil
+}
+
+// ─── Tool handler ────────────────────────────────────────────────────────────
+
+type RunReQLInput struct {
+	Query     string `json:"query" jsonschema:"ReQL expression in JavaScript syntax, e.g. r.db('app').table('users').filter(r.row('age').gt(30)).pluck('name')"`
+	Limit     int    `json:"limit,omitempty" jsonschema:"Maximum number of results (default 100, max 1000)"`
+	TimeoutMs int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+type RunReQLOutput struct {
+	Query     string `json:"query"`
+	Count     int    `json:"count"`
+	Results   []any  `json:"results"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+func (s *RethinkDBServer) RunReQL(ctx context.Context, req *mcp.CallToolRequest, input RunReQLInput) (*mcp.CallToolResult, RunReQLOutput, error) {
+	if strings.TrimSpace(input.Query) == "" {
+		return nil, RunReQLOutput{}, fmt.Errorf("query is required")
+	}
+
+	parsed, err := parseReQL(input.Query, s.reqlPerms)
+	if err != nil {
+		return nil, RunReQLOutput{}, fmt.Errorf("invalid query: %w", err)
+	}
+
+	for _, db := range parsed.Databases {
+		if err := s.checkDatabase(db); err != nil {
+			return nil, RunReQLOutput{}, err
+		}
+	}
+	for _, ref := range parsed.Tables {
+		if err := s.checkTables(ref.Database, ref.Table); err != nil {
+			return nil, RunReQLOutput{}, err
+		}
+	}
+	// A write may reach a reserved table through any table the query
+	// names, so a query that writes may not name one at all.
+	found, err := findWriteTerm(parsed.Term)
+	if err != nil {
+		return nil, RunReQLOutput{}, fmt.Errorf("failed to inspect query: %w", err)
+	}
+	if found != nil {
+		for _, ref := range parsed.Tables {
+			if err := s.checkReservedTables(ref.Database, ref.Table); err != nil {
+				return nil, RunReQLOutput{}, err
+			}
+		}
+	}
+
+	if err := s.checkReadOnly(parsed.Term); err != nil {
+		return nil, RunReQLOutput{}, err
+	}
+
+	limit := applyLimit(input.Limit)
+
+	ctx, cancel := s.withTimeout(ctx, "run_reql", input.TimeoutMs)
+	defer cancel()
+	what := describeQuery("run_reql", parsed.Term)
+
+	cursor, err := parsed.Term.Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, RunReQLOutput{}, queryError(ctx, what, fmt.Errorf("failed to execute query: %w", err))
+	}
+	defer cursor.Close()
+
+	output := RunReQLOutput{Query: input.Query, Results: []any{}}
+	var doc interface{}
+	for cursor.Next(&doc) {
+		if len(output.Results) == limit {
+			output.Truncated = true
+			break
+		}
+		output.Results = append(output.Results, doc)
+		doc = nil
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, RunReQLOutput{}, queryError(ctx, what, fmt.Errorf("failed to read results: %w", err))
+	}
+	output.Count = len(output.Results)
+
+	return nil, output, nil
+}
+
+// ─── Lexer ───────────────────────────────────────────────────────────────────
+
+type reqlTokenKind int
+
+const (
+	tokEOF reqlTokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokPunct
+)
+
+type reqlToken struct {
+	kind reqlTokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+func lexReQL(src string) ([]reqlToken, error) {
+	var toks []reqlToken
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || c == '$' || unicode.IsLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] == '$' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			toks = append(toks, reqlToken{kind: tokIdent, text: src[start:i], pos: start})
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(src) && (unicode.IsDigit(rune(src[i+1])) || src[i+1] == '.')) || (c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			start := i
+			i++
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || strings.ContainsRune(".eE", rune(src[i])) ||
+				((src[i] == '+' || src[i] == '-') && (src[i-1] == 'e' || src[i-1] == 'E'))) {
+				i++
+			}
+			num, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", src[start:i], start)
+			}
+			toks = append(toks, reqlToken{kind: tokNumber, text: src[start:i], num: num, pos: start})
+		case c == '\'' || c == '"':
+			start := i
+			str, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%v at offset %d", err, start)
+			}
+			i += n
+			toks = append(toks, reqlToken{kind: tokString, text: str, pos: start})
+		case c == '=' && i+1 < len(src) && src[i+1] == '>':
+			toks = append(toks, reqlToken{kind: tokPunct, text: "=>", pos: i})
+			i += 2
+		case strings.ContainsRune("()[]{},.:;", c):
+			toks = append(toks, reqlToken{kind: tokPunct, text: string(c), pos: i})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+		}
+	}
+	return append(toks, reqlToken{kind: tokEOF, pos: len(src)}), nil
+}
+
+// lexString reads a quoted string at the start of s and returns its value
+// and the number of bytes consumed.
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\':
+			i++
+			if i >= len(s) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'u':
+				if i+4 >= len(s) {
+					return "", 0, fmt.Errorf("invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(s[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid unicode escape")
+				}
+				b.WriteRune(rune(code))
+				i += 4
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// ─── Parser ──────────────────────────────────────────────────────────────────
+
+// reqlValue is a parsed expression in wire-protocol form.
+type reqlValue struct {
+	wire interface{}
+	// object holds the fields of an object literal, so that a trailing
+	// literal can be recognised as optional arguments.
+	object map[string]interface{}
+	// root marks the bare r namespace.
+	root bool
+	// implicit marks an expression that uses r.row outside of a function;
+	// it is wrapped in a function when passed as a method argument.
+	implicit bool
+	// db is the literal database name when the value is r.db('<name>').
+	db string
+	// literal marks a string written as a literal in the query, the only
+	// form r.db and table accept as a name.
+	literal bool
+}
+
+type reqlParser struct {
+	toks      []reqlToken
+	pos       int
+	perms     ReQLPermissions
+	scope     map[string]int
+	nextVar   int
+	databases []string
+	tables    []reqlTableRef
+}
+
+func (ps *reqlParser) peek() reqlToken { return ps.toks[ps.pos] }
+
+func (ps *reqlParser) next() reqlToken {
+	tok := ps.toks[ps.pos]
+	if tok.kind != tokEOF {
+		ps.pos++
+	}
+	return tok
+}
+
+func (ps *reqlParser) isPunct(text string) bool {
+	tok := ps.peek()
+	return tok.kind == tokPunct && tok.text == text
+}
+
+func (ps *reqlParser) expect(text string) error {
+	tok := ps.next()
+	if tok.kind != tokPunct || tok.text != text {
+		return ps.errorf(tok, "expected %q", text)
+	}
+	return nil
+}
+
+func (ps *reqlParser) errorf(tok reqlToken, format string, args ...interface{}) error {
+	return fmt.Errorf("parse error at offset %d: %s", tok.pos, fmt.Sprintf(format, args...))
+}
+
+func (ps *reqlParser) parseExpr() (reqlValue, error) {
+	v, err := ps.parsePrimary()
+	if err != nil {
+		return reqlValue{}, err
+	}
+	for {
+		switch {
+		case ps.isPunct("."):
+			ps.next()
+			name := ps.next()
+			if name.kind != tokIdent {
+				return reqlValue{}, ps.errorf(name, "expected method name")
+			}
+			if !ps.isPunct("(") || (v.root && isReQLProperty(name.text)) {
+				if v, err = ps.property(v, name); err != nil {
+					return reqlValue{}, err
+				}
+				continue
+			}
+			args, err := ps.parseArgs()
+			if err != nil {
+				return reqlValue{}, err
+			}
+			if v, err = ps.call(v, name, args); err != nil {
+				return reqlValue{}, err
+			}
+		case ps.isPunct("("):
+			tok := ps.peek()
+			if v.root {
+				return reqlValue{}, ps.errorf(tok, "r cannot be called directly")
+			}
+			args, err := ps.parseArgs()
+			if err != nil {
+				return reqlValue{}, err
+			}
+			if len(args) != 1 {
+				return reqlValue{}, ps.errorf(tok, "field access takes exactly one argument")
+			}
+			v = ps.term(p.Term_BRACKET, []reqlValue{v, args[0]}, nil, v.implicit)
+		default:
+			return v, nil
+		}
+	}
+}
+
+// isReQLProperty reports whether r.<name> is accessed without a call.
+func isReQLProperty(name string) bool {
+	return name == "row" || name == "minval" || name == "maxval"
+}
+
+// property handles attribute access without a call, which ReQL only uses
+// for r.row, r.minval and r.maxval.
+func (ps *reqlParser) property(v reqlValue, name reqlToken) (reqlValue, error) {
+	if !v.root {
+		return reqlValue{}, ps.errorf(name, "%q must be called as a method", name.text)
+	}
+	switch name.text {
+	case "row":
+		return reqlValue{wire: []interface{}{int(p.Term_IMPLICIT_VAR)}, implicit: true}, nil
+	case "minval":
+		return reqlValue{wire: []interface{}{int(p.Term_MINVAL)}}, nil
+	case "maxval":
+		return reqlValue{wire: []interface{}{int(p.Term_MAXVAL)}}, nil
+	}
+	return reqlValue{}, ps.errorf(name, "r.%s is not allowed", name.text)
+}
+
+// call builds a method call on v, or an r.* function call when v is r.
+func (ps *reqlParser) call(v reqlValue, name reqlToken, args []reqlValue) (reqlValue, error) {
+	methods, prefix := reqlMethods, "."
+	if v.root {
+		methods, prefix = reqlRootFunctions, "r."
+	}
+	m, ok := methods[name.text]
+	if !ok {
+		return reqlValue{}, ps.errorf(name, "%s%s is not allowed", prefix, name.text)
+	}
+	switch m.gate {
+	case gateWrite:
+		if !ps.perms.Writes {
+			return reqlValue{}, ps.errorf(name, "%s%s is a write and writes are not enabled", prefix, name.text)
+		}
+	case gateJavaScript:
+		if !ps.perms.JavaScript {
+			return reqlValue{}, ps.errorf(name, "r.js is not enabled")
+		}
+	case gateHTTP:
+		if !ps.perms.HTTP {
+			return reqlValue{}, ps.errorf(name, "r.http is not enabled")
+		}
+	}
+
+	// A trailing object literal whose keys are all optional argument names
+	// is sent as optional arguments.
+	var optArgs map[string]interface{}
+	if n := len(args); n > m.minArgs && len(m.optArgs) > 0 && args[n-1].object != nil && onlyKeys(args[n-1].object, m.optArgs) {
+		optArgs = make(map[string]interface{}, len(args[n-1].object))
+		for k, val := range args[n-1].object {
+			optArgs[toSnakeCase(k)] = val
+		}
+		args = args[:n-1]
+	}
+	if len(args) < m.minArgs {
+		return reqlValue{}, ps.errorf(name, "%s%s requires at least %d argument(s)", prefix, name.text, m.minArgs)
+	}
+
+	if v.root {
+		switch name.text {
+		case "expr":
+			if len(args) != 1 {
+				return reqlValue{}, ps.errorf(name, "r.expr takes exactly one argument")
+			}
+			arg := args[0]
+			arg.literal = false
+			return arg, nil
+		case "db":
+			db, ok := args[0].wire.(string)
+			if !ok || !args[0].literal || len(args) != 1 {
+				return reqlValue{}, ps.errorf(name, "r.db requires a single literal database name")
+			}
+			ps.databases = append(ps.databases, db)
+			res := ps.term(m.term, args, optArgs, false)
+			res.db = db
+			return res, nil
+		case "do":
+			// r.do(args..., func) becomes FUNCALL(func, args...).
+			fn := args[len(args)-1]
+			return ps.term(m.term, append([]reqlValue{fn}, args[:len(args)-1]...), optArgs, anyImplicit(args)), nil
+		}
+		return ps.term(m.term, args, optArgs, anyImplicit(args)), nil
+	}
+
+	switch name.text {
+	case "table":
+		table, ok := args[0].wire.(string)
+		if v.db == "" || !ok || !args[0].literal || len(args) != 1 {
+			return reqlValue{}, ps.errorf(name, "table must be called on r.db('<name>') with a single literal table name")
+		}
+		ps.tables = append(ps.tables, reqlTableRef{Database: v.db, Table: table})
+	case "do":
+		// x.do(args..., func) becomes FUNCALL(func, x, args...).
+		fn := ps.wrapImplicit(args[len(args)-1], v.implicit)
+		rest := append([]reqlValue{v}, args[:len(args)-1]...)
+		return ps.term(m.term, append([]reqlValue{fn}, rest...), optArgs, v.implicit), nil
+	}
+
+	for i := range args {
+		args[i] = ps.wrapImplicit(args[i], v.implicit)
+	}
+	return ps.term(m.term, append([]reqlValue{v}, args...), optArgs, v.implicit), nil
+}
+
+// wrapImplicit wraps an argument that uses r.row in a one-argument function,
+// as the official drivers do, unless the receiver itself uses r.row.
+func (ps *reqlParser) wrapImplicit(arg reqlValue, receiverImplicit bool) reqlValue {
+	if !arg.implicit || receiverImplicit {
+		return arg
+	}
+	ps.nextVar++
+	params := []interface{}{int(p.Term_MAKE_ARRAY), []interface{}{ps.nextVar}}
+	return reqlValue{wire: []interface{}{int(p.Term_FUNC), []interface{}{params, arg.wire}}}
+}
+
+func (ps *reqlParser) term(typ p.Term_TermType, args []reqlValue, optArgs map[string]interface{}, implicit bool) reqlValue {
+	wireArgs := make([]interface{}, len(args))
+	for i, arg := range args {
+		wireArgs[i] = arg.wire
+	}
+	wire := []interface{}{int(typ), wireArgs}
+	if len(optArgs) > 0 {
+		wire = append(wire, optArgs)
+	}
+	return reqlValue{wire: wire, implicit: implicit}
+}
+
+func (ps *reqlParser) parseArgs() ([]reqlValue, error) {
+	if err := ps.expect("("); err != nil {
+		return nil, err
+	}
+	var args []reqlValue
+	for !ps.isPunct(")") {
+		arg, err := ps.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if arg.root {
+			return nil, ps.errorf(ps.peek(), "r cannot be used as a value")
+		}
+		args = append(args, arg)
+		if !ps.isPunct(",") {
+			break
+		}
+		ps.next()
+	}
+	if err := ps.expect(")"); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func (ps *reqlParser) parsePrimary() (reqlValue, error) {
+	tok := ps.peek()
+	switch tok.kind {
+	case tokNumber:
+		ps.next()
+		return reqlValue{wire: tok.num}, nil
+	case tokString:
+		ps.next()
+		return reqlValue{wire: tok.text, literal: true}, nil
+	case tokIdent:
+		if ps.isArrowFunction() {
+			return ps.parseArrowFunction()
+		}
+		ps.next()
+		switch tok.text {
+		case "r":
+			return reqlValue{root: true}, nil
+		case "true":
+			return reqlValue{wire: true}, nil
+		case "false":
+			return reqlValue{wire: false}, nil
+		case "null":
+			return reqlValue{wire: nil}, nil
+		case "function":
+			return ps.parseFunction()
+		}
+		if id, ok := ps.scope[tok.text]; ok {
+			return reqlValue{wire: []interface{}{int(p.Term_VAR), []interface{}{id}}}, nil
+		}
+		return reqlValue{}, ps.errorf(tok, "unknown identifier %q", tok.text)
+	case tokPunct:
+		switch tok.text {
+		case "[":
+			return ps.parseArray()
+		case "{":
+			return ps.parseObject()
+		case "(":
+			if ps.isArrowFunction() {
+				return ps.parseArrowFunction()
+			}
+			ps.next()
+			v, err := ps.parseExpr()
+			if err != nil {
+				return reqlValue{}, err
+			}
+			return v, ps.expect(")")
+		}
+	}
+	return reqlValue{}, ps.errorf(tok, "unexpected %q", tok.text)
+}
+
+func (ps *reqlParser) parseArray() (reqlValue, error) {
+	ps.next()
+	var elems []interface{}
+	implicit := false
+	for !ps.isPunct("]") {
+		v, err := ps.parseExpr()
+		if err != nil {
+			return reqlValue{}, err
+		}
+		if v.root {
+			return reqlValue{}, ps.errorf(ps.peek(), "r cannot be used as a value")
+		}
+		elems = append(elems, v.wire)
+		implicit = implicit || v.implicit
+		if !ps.isPunct(",") {
+			break
+		}
+		ps.next()
+	}
+	if err := ps.expect("]"); err != nil {
+		return reqlValue{}, err
+	}
+	if elems == nil {
+		elems = []interface{}{}
+	}
+	return reqlValue{wire: []interface{}{int(p.Term_MAKE_ARRAY), elems}, implicit: implicit}, nil
+}
+
+func (ps *reqlParser) parseObject() (reqlValue, error) {
+	ps.next()
+	obj := map[string]interface{}{}
+	implicit := false
+	for !ps.isPunct("}") {
+		key := ps.next()
+		if key.kind != tokIdent && key.kind != tokString {
+			return reqlValue{}, ps.errorf(key, "expected object key")
+		}
+		if err := ps.expect(":"); err != nil {
+			return reqlValue{}, err
+		}
+		v, err := ps.parseExpr()
+		if err != nil {
+			return reqlValue{}, err
+		}
+		if v.root {
+			return reqlValue{}, ps.errorf(ps.peek(), "r cannot be used as a value")
+		}
+		obj[key.text] = v.wire
+		implicit = implicit || v.implicit
+		if !ps.isPunct(",") {
+			break
+		}
+		ps.next()
+	}
+	if err := ps.expect("}"); err != nil {
+		return reqlValue{}, err
+	}
+	return reqlValue{wire: obj, object: obj, implicit: implicit}, nil
+}
+
+// isArrowFunction reports whether the tokens at the current position start
+// an arrow function: "x => ..." or "(x, y) => ...".
+func (ps *reqlParser) isArrowFunction() bool {
+	tok := ps.peek()
+	if tok.kind == tokIdent {
+		next := ps.toks[ps.pos+1]
+		return next.kind == tokPunct && next.text == "=>"
+	}
+	if tok.kind != tokPunct || tok.text != "(" {
+		return false
+	}
+	for i := ps.pos + 1; i < len(ps.toks); i++ {
+		t := ps.toks[i]
+		switch {
+		case t.kind == tokIdent || (t.kind == tokPunct && t.text == ","):
+			continue
+		case t.kind == tokPunct && t.text == ")":
+			next := ps.toks[i+1]
+			return next.kind == tokPunct && next.text == "=>"
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+func (ps *reqlParser) parseArrowFunction() (reqlValue, error) {
+	var params []string
+	if ps.isPunct("(") {
+		var err error
+		if params, err = ps.parseParams(); err != nil {
+			return reqlValue{}, err
+		}
+	} else {
+		params = []string{ps.next().text}
+	}
+	if err := ps.expect("=>"); err != nil {
+		return reqlValue{}, err
+	}
+	if ps.isPunct("{") {
+		return ps.parseFunctionBody(params)
+	}
+	return ps.withParams(params, ps.parseExpr)
+}
+
+func (ps *reqlParser) parseFunction() (reqlValue, error) {
+	params, err := ps.parseParams()
+	if err != nil {
+		return reqlValue{}, err
+	}
+	return ps.parseFunctionBody(params)
+}
+
+// parseFunctionBody parses "{ return <expr>; }".
+func (ps *reqlParser) parseFunctionBody(params []string) (reqlValue, error) {
+	if err := ps.expect("{"); err != nil {
+		return reqlValue{}, err
+	}
+	if tok := ps.next(); tok.kind != tokIdent || tok.text != "return" {
+		return reqlValue{}, ps.errorf(tok, "function body must be a single return statement")
+	}
+	fn, err := ps.withParams(params, ps.parseExpr)
+	if err != nil {
+		return reqlValue{}, err
+	}
+	if ps.isPunct(";") {
+		ps.next()
+	}
+	return fn, ps.expect("}")
+}
+
+func (ps *reqlParser) parseParams() ([]string, error) {
+	if err := ps.expect("("); err != nil {
+		return nil, err
+	}
+	var params []string
+	for !ps.isPunct(")") {
+		tok := ps.next()
+		if tok.kind != tokIdent || tok.text == "r" {
+			return nil, ps.errorf(tok, "expected parameter name")
+		}
+		params = append(params, tok.text)
+		if !ps.isPunct(",") {
+			break
+		}
+		ps.next()
+	}
+	return params, ps.expect(")")
+}
+
+// withParams parses a function body with params bound to fresh variables and
+// returns the FUNC term.
+func (ps *reqlParser) withParams(params []string, body func() (reqlValue, error)) (reqlValue, error) {
+	saved := ps.scope
+	ps.scope = make(map[string]int, len(saved)+len(params))
+	for k, v := range saved {
+		ps.scope[k] = v
+	}
+	ids := make([]interface{}, len(params))
+	for i, name := range params {
+		ps.nextVar++
+		ps.scope[name] = ps.nextVar
+		ids[i] = ps.nextVar
+	}
+	v, err := body()
+	ps.scope = saved
+	if err != nil {
+		return reqlValue{}, err
+	}
+	if v.root {
+		return reqlValue{}, fmt.Errorf("function must not return the bare r namespace")
+	}
+	paramList := []interface{}{int(p.Term_MAKE_ARRAY), ids}
+	return reqlValue{wire: []interface{}{int(p.Term_FUNC), []interface{}{paramList, v.wire}}}, nil
+}
+
+// funcArity returns the number of parameters of a FUNC term in wire form, or
+// -1 if wire is not a function.
+func funcArity(wire interface{}) int {
+	term, ok := wire.([]interface{})
+	if !ok || len(term) != 2 || term[0] != int(p.Term_FUNC) {
+		return -1
+	}
+	args := term[1].([]interface{})
+	params := args[0].([]interface{})
+	return len(params[1].([]interface{}))
+}
+
+func anyImplicit(args []reqlValue) bool {
+	for _, arg := range args {
+		if arg.implicit {
+			return true
+		}
+	}
+	return false
+}
+
+func onlyKeys(obj map[string]interface{}, allowed []string) bool {
+	for k := range obj {
+		found := false
+		for _, a := range allowed {
+			if k == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// toSnakeCase converts a JavaScript optional argument name such as
+// "returnChanges" into its wire form "return_changes".
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	for i, c := range s {
+		if unicode.IsUpper(c) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			c = unicode.ToLower(c)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
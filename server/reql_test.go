@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ─── run_reql ───────────────────────────────────────────────────────────────
+
+func TestParseReQL_RejectsUnsafeTerms(t *testing.T) {
+	queries := []string{
+		`r.js('1 + 1')`,
+		`r.http('http://example.com')`,
+		`r.db('x').table('y').insert({a: 1})`,
+		`r.db('x').table('y').get('1').update({a: 2})`,
+		`r.db('x').table('y').delete()`,
+		`r.db('x').table('y').changes()`,
+		`r.dbList()`,
+		`r.db('x').tableList()`,
+		`r.table('y')`,
+		`r.db(r.expr('x')).table('y')`,
+		`r.db('x').table('y').filter(`,
+		`r.db('x').table('y') + 1`,
+	}
+	for _, q := range queries {
+		if _, err := parseReQL(q, ReQLPermissions{}); err == nil {
+			t.Errorf("expected %s to be rejected", q)
+		}
+	}
+}
+
+func TestParseReQL_PermissionsEnableGatedTerms(t *testing.T) {
+	perms := ReQLPermissions{JavaScript: true, HTTP: true, Writes: true}
+	queries := []string{
+		`r.js('1 + 1')`,
+		`r.http('http://example.com', {timeout: 5})`,
+		`r.db('x').table('y').insert({a: 1}, {returnChanges: true})`,
+	}
+	for _, q := range queries {
+		if _, err := parseReQL(q, perms); err != nil {
+			t.Errorf("expected %s to parse, got %v", q, err)
+		}
+	}
+}
+
+func TestParseReQL_CollectsTables(t *testing.T) {
+	parsed, err := parseReQL(`r.db('a').table('b').eqJoin('id', r.db('a').table('c'), {ordered: true})`, ReQLPermissions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(parsed.Tables) != 2 || parsed.Tables[0].Table != "b" || parsed.Tables[1].Table != "c" {
+		t.Errorf("expected tables b and c, got %v", parsed.Tables)
+	}
+}
+
+func TestRunReQL_FilterWithRow(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.RunReQL(context.Background(), &mcp.CallToolRequest{}, RunReQLInput{
+		Query: `r.db('` + testDB + `').table('` + testTable + `').filter(r.row('age').gt(28)).pluck('name')`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Count != 2 {
+		t.Errorf("expected 2 results, got %d", output.Count)
+	}
+}
+
+func TestRunReQL_FunctionsAndAggregation(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.RunReQL(context.Background(), &mcp.CallToolRequest{}, RunReQLInput{
+		Query: `r.db('` + testDB + `').table('` + testTable + `').filter(function(doc) { return doc('status').eq('active'); }).map(d => d('age')).sum()`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Count != 1 || output.Results[0] != float64(65) {
+		t.Errorf("expected sum 65, got %v", output.Results)
+	}
+}
+
+func TestRunReQL_LimitTruncates(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.RunReQL(context.Background(), &mcp.CallToolRequest{}, RunReQLInput{
+		Query: `r.db('` + testDB + `').table('` + testTable + `')`,
+		Limit: 2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Count != 2 || !output.Truncated {
+		t.Errorf("expected 2 truncated results, got %d (truncated=%v)", output.Count, output.Truncated)
+	}
+}
+
+func TestRunReQL_PolicyAndReadOnlyEnforced(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithPolicy(&Policy{DenyTables: []string{"*." + testJoinTable}}))
+	_, _, err := srv.RunReQL(context.Background(), &mcp.CallToolRequest{}, RunReQLInput{
+		Query: `r.db('` + testDB + `').table('` + testJoinTable + `')`,
+	})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("expected ErrAccessDenied, got %v", err)
+	}
+
+	// Listing the database's tables would reveal the denied table's name.
+	_, _, err = srv.RunReQL(context.Background(), &mcp.CallToolRequest{}, RunReQLInput{
+		Query: `r.db('` + testDB + `').tableList()`,
+	})
+	if err == nil {
+		t.Error("expected tableList to be rejected")
+	}
+
+	srv = NewRethinkDBServer(testSession, WithReadOnly(true), WithReQLPermissions(ReQLPermissions{Writes: true}))
+	_, _, err = srv.RunReQL(context.Background(), &mcp.CallToolRequest{}, RunReQLInput{
+		Query: `r.db('` + testDB + `').table('` + testTable + `').insert({id: 'reql-ro'})`,
+	})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
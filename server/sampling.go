@@ -0,0 +1,249 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// Sampling strategies for schema_inspector.
+const (
+	// sampleFirst reads the first documents the table returns.
+	sampleFirst = "first"
+	// sampleRandom draws a uniform random sample with Sample.
+	sampleRandom = "random"
+	// sampleRecent reads the newest documents by a time index.
+	sampleRecent = "recent"
+	// sampleStratified reads evenly from ranges of the primary key.
+	sampleStratified = "stratified"
+)
+
+// sampleStrata is how many primary key ranges a stratified sample spans.
+const sampleStrata = 10
+
+// recentIndexNames are the index names tried, in order, when the recent
+// strategy is used without a time_index.
+var recentIndexNames = []string{"created_at", "createdAt", "updated_at", "updatedAt", "timestamp", "created", "time"}
+
+// sampleRequest describes the documents to sample from a table.
+type sampleRequest struct {
+	strategy  string
+	size      int
+	timeIndex string
+	pk        string
+	indexes   []string
+	docCount  int
+}
+
+// sampleResult is a sample and how it was drawn. strategy differs from the
+// requested one when the request could not be honored as is.
+type sampleResult struct {
+	docs     []map[string]interface{}
+	strategy string
+	note     string
+}
+
+// parseSampleStrategy validates a strategy name; empty means first, which
+// reads only the sampled documents. random is opt-in because RethinkDB reads
+// the whole table to draw it.
+func parseSampleStrategy(name string) (string, error) {
+	switch name {
+	case "":
+		return sampleFirst, nil
+	case sampleFirst, sampleRandom, sampleRecent, sampleStratified:
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown sampling strategy %q: use first, random, recent or stratified", name)
+}
+
+// sampleDocuments draws a sample from db.table according to req.
+func (s *RethinkDBServer) sampleDocuments(ctx context.Context, db, table string, req sampleRequest) (sampleResult, error) {
+	t := r.DB(db).Table(table)
+	result := sampleResult{strategy: req.strategy}
+
+	var query r.Term
+	switch req.strategy {
+	case sampleFirst:
+		query = t.Limit(req.size)
+		result.note = "first reads one contiguous range of the table; fields that only appear elsewhere may be missed"
+	case sampleRecent:
+		index, err := recentIndex(req.timeIndex, req.indexes)
+		if err != nil {
+			return sampleResult{}, err
+		}
+		query = t.OrderBy(r.OrderByOpts{Index: r.Desc(index)}).Limit(req.size)
+		result.note = fmt.Sprintf("recent reads the newest documents by the %s index; older documents may differ", index)
+	case sampleStratified:
+		if req.docCount == 0 {
+			query = t.Limit(req.size)
+			break
+		}
+		bounds, err := s.primaryKeyBounds(ctx, t, req.pk)
+		if err != nil {
+			return sampleResult{}, err
+		}
+		splits, ok := splitKeyRange(bounds[0], bounds[1], sampleStrata)
+		if !ok {
+			query = t.Sample(req.size)
+			result.strategy = sampleRandom
+			result.note = "primary keys are not all numbers or all strings, so a random sample was drawn instead"
+			break
+		}
+		query = stratifiedSample(t, req.pk, splits, req.size)
+	default:
+		query = t.Sample(req.size)
+	}
+
+	cursor, err := query.Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return sampleResult{}, fmt.Errorf("failed to sample documents: %w", err)
+	}
+	defer cursor.Close()
+	if err := cursor.All(&result.docs); err != nil {
+		return sampleResult{}, fmt.Errorf("failed to read sample documents: %w", err)
+	}
+	return result, nil
+}
+
+// recentIndex returns the index the recent strategy orders by: the given
+// one if it exists, otherwise the first of recentIndexNames that does.
+func recentIndex(timeIndex string, indexes []string) (string, error) {
+	if timeIndex != "" {
+		if !containsString(indexes, timeIndex) {
+			return "", fmt.Errorf("time_index %q does not exist", timeIndex)
+		}
+		return timeIndex, nil
+	}
+	for _, name := range recentIndexNames {
+		if containsString(indexes, name) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("the recent strategy needs a time_index: no index named %s exists", strings.Join(recentIndexNames, ", "))
+}
+
+// primaryKeyBounds returns the smallest and largest primary key of t.
+func (s *RethinkDBServer) primaryKeyBounds(ctx context.Context, t r.Term, pk string) ([]interface{}, error) {
+	cursor, err := r.Expr([]interface{}{
+		t.MinIndex(pk).Field(pk),
+		t.MaxIndex(pk).Field(pk),
+	}).Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read primary key range: %w", err)
+	}
+	defer cursor.Close()
+	var bounds []interface{}
+	if err := cursor.One(&bounds); err != nil {
+		return nil, fmt.Errorf("failed to read primary key range: %w", err)
+	}
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("failed to read primary key range")
+	}
+	return bounds, nil
+}
+
+// stratifiedSample reads size documents spread evenly over the primary key
+// ranges delimited by splits.
+func stratifiedSample(t r.Term, pk string, splits []interface{}, size int) r.Term {
+	strata := len(splits) + 1
+	per := (size + strata - 1) / strata
+	ranges := make([]interface{}, 0, strata)
+	lower := interface{}(r.MinVal)
+	for i := 0; i < strata; i++ {
+		upper := interface{}(r.MaxVal)
+		if i < len(splits) {
+			upper = splits[i]
+		}
+		ranges = append(ranges, t.Between(lower, upper, r.BetweenOpts{Index: pk}).Limit(per))
+		lower = upper
+	}
+	return r.Union(ranges...).Limit(size)
+}
+
+// splitKeyRange returns the n-1 keys that split [min, max] into n ranges of
+// equal width. Numbers are split arithmetically; strings by their leading
+// characters read as base-128 digits, which suits generated keys such as
+// UUIDs. It reports false for other key types.
+func splitKeyRange(min, max interface{}, n int) ([]interface{}, bool) {
+	switch lo := min.(type) {
+	case float64:
+		hi, ok := max.(float64)
+		if !ok {
+			return nil, false
+		}
+		splits := make([]interface{}, 0, n-1)
+		for i := 1; i < n; i++ {
+			splits = append(splits, lo+(hi-lo)*float64(i)/float64(n))
+		}
+		return dedupeSplits(splits), true
+	case string:
+		hi, ok := max.(string)
+		if !ok {
+			return nil, false
+		}
+		a, b := stringKeyValue(lo), stringKeyValue(hi)
+		splits := make([]interface{}, 0, n-1)
+		for i := 1; i < n; i++ {
+			splits = append(splits, stringKey(a+(b-a)/uint64(n)*uint64(i)))
+		}
+		return dedupeSplits(splits), true
+	}
+	return nil, false
+}
+
+// stringKeyDigits is how many leading characters of a string key are used
+// when splitting string ranges; 8 base-128 digits fit in 56 bits.
+const stringKeyDigits = 8
+
+func stringKeyValue(s string) uint64 {
+	var v uint64
+	for i := 0; i < stringKeyDigits; i++ {
+		var digit byte
+		if i < len(s) {
+			digit = s[i]
+			if digit > 0x7f {
+				digit = 0x7f
+			}
+		}
+		v = v<<7 | uint64(digit)
+	}
+	return v
+}
+
+func stringKey(v uint64) string {
+	digits := make([]byte, stringKeyDigits)
+	for i := stringKeyDigits - 1; i >= 0; i-- {
+		digits[i] = byte(v & 0x7f)
+		v >>= 7
+	}
+	return strings.TrimRight(string(digits), "\x00")
+}
+
+// dedupeSplits drops repeated split keys, which occur when the key range is
+// narrower than the number of strata.
+func dedupeSplits(splits []interface{}) []interface{} {
+	out := splits[:0]
+	for i, split := range splits {
+		if i == 0 || split != splits[i-1] {
+			out = append(out, split)
+		}
+	}
+	return out
+}
+
+// sampleConfidence estimates how representative a sample of sampled out of
+// docCount documents is: the probability that a field present in at least
+// 1% of documents appears in a random sample of that size. It is 1 when the
+// whole table was read.
+func sampleConfidence(sampled, docCount int) float64 {
+	if sampled == 0 {
+		return 0
+	}
+	if sampled >= docCount {
+		return 1
+	}
+	return math.Round((1-math.Pow(0.99, float64(sampled)))*1000) / 1000
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ─── schema sampling ────────────────────────────────────────────────────────
+
+func TestParseSampleStrategy(t *testing.T) {
+	if strategy, err := parseSampleStrategy(""); err != nil || strategy != sampleFirst {
+		t.Errorf("expected first by default, got %q, %v", strategy, err)
+	}
+	if _, err := parseSampleStrategy("newest"); err == nil {
+		t.Error("expected an unknown strategy to be rejected")
+	}
+}
+
+func TestSplitKeyRange(t *testing.T) {
+	splits, ok := splitKeyRange(0.0, 100.0, 4)
+	if !ok || len(splits) != 3 || splits[0] != 25.0 || splits[2] != 75.0 {
+		t.Errorf("unexpected numeric splits: %v", splits)
+	}
+
+	splits, ok = splitKeyRange("00000000-0000", "ffffffff-ffff", 10)
+	if !ok || len(splits) != 9 {
+		t.Fatalf("unexpected string splits: %v", splits)
+	}
+	prev := "00000000-0000"
+	for _, split := range splits {
+		if s := split.(string); s <= prev || s >= "ffffffff-ffff" {
+			t.Errorf("expected increasing splits inside the range, got %v", splits)
+		}
+		prev = split.(string)
+	}
+
+	if splits, ok := splitKeyRange("a", "a", 10); !ok || len(splits) != 1 {
+		t.Errorf("expected a single split for an empty range, got %v", splits)
+	}
+	if _, ok := splitKeyRange("a", 1.0, 10); ok {
+		t.Error("expected mixed key types to be rejected")
+	}
+}
+
+func TestRecentIndex(t *testing.T) {
+	if index, err := recentIndex("", []string{"status", "updated_at"}); err != nil || index != "updated_at" {
+		t.Errorf("expected updated_at, got %q, %v", index, err)
+	}
+	if _, err := recentIndex("", []string{"status"}); err == nil {
+		t.Error("expected an error without a time index")
+	}
+	if _, err := recentIndex("missing", []string{"status"}); err == nil {
+		t.Error("expected an error for an unknown time_index")
+	}
+}
+
+func TestSampleConfidence(t *testing.T) {
+	if c := sampleConfidence(3, 3); c != 1 {
+		t.Errorf("expected full confidence for a whole table, got %v", c)
+	}
+	if c := sampleConfidence(100, 100000); c != 0.634 {
+		t.Errorf("expected 0.634, got %v", c)
+	}
+	if c := sampleConfidence(0, 10); c != 0 {
+		t.Errorf("expected no confidence without a sample, got %v", c)
+	}
+}
+
+func TestSchemaInspector_Strategies(t *testing.T) {
+	srv := newTestServer()
+	for _, strategy := range []string{"first", "random", "stratified"} {
+		_, output, err := srv.SchemaInspector(context.Background(), &mcp.CallToolRequest{}, SchemaInspectorInput{
+			Database: testDB,
+			Table:    testTable,
+			Strategy: strategy,
+		})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", strategy, err)
+		}
+		if output.Strategy != strategy || output.Sampled != 3 || output.Confidence != 1 {
+			t.Errorf("%s: unexpected output %+v", strategy, output)
+		}
+	}
+}
+
+func TestSchemaInspector_Recent(t *testing.T) {
+	srv := newTestServer()
+	_, _, err := srv.SchemaInspector(context.Background(), &mcp.CallToolRequest{}, SchemaInspectorInput{
+		Database: testDB,
+		Table:    testTable,
+		Strategy: "recent",
+	})
+	if err == nil {
+		t.Fatal("expected an error without a time index")
+	}
+
+	_, output, err := srv.SchemaInspector(context.Background(), &mcp.CallToolRequest{}, SchemaInspectorInput{
+		Database:   testDB,
+		Table:      testTable,
+		Strategy:   "recent",
+		TimeIndex:  "age",
+		SampleSize: 1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Sampled != 1 || output.Note == "" {
+		t.Errorf("unexpected output %+v", output)
+	}
+	// Ordered by age descending, Charlie (35) comes first.
+	if name := fieldByName(output.Fields, "name"); name == nil || name.Samples[0] != "Charlie" {
+		t.Errorf("expected Charlie to be sampled, got %+v", name)
+	}
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/types"
+)
+
+const (
+	// maxSchemaDepth bounds how deep nested objects are walked.
+	maxSchemaDepth = 10
+	// maxSchemaSamples is how many distinct sample values are kept per field.
+	maxSchemaSamples = 3
+	// maxSampleLength truncates long string samples.
+	maxSampleLength = 80
+)
+
+// FieldInfo describes one field observed in the sampled documents. Nested
+// fields are named by dotted path ("address.city"), and fields of objects
+// inside arrays by the array path followed by "[]" ("items[].sku").
+type FieldInfo struct {
+	Name string `json:"name"`
+	// Type lists the observed types, most frequent first, joined by "|".
+	Type string `json:"type"`
+	// Types counts each observed type: string, number, bool, null, array,
+	// object, or one of the RethinkDB pseudo-types time, binary and geometry.
+	Types map[string]int `json:"types"`
+	// Count is how many times the field was seen.
+	Count int `json:"count"`
+	// Presence is the percentage of parent objects containing the field: of
+	// the sampled documents for a top-level field, of the objects seen at
+	// the parent path for a nested one.
+	Presence float64 `json:"presence"`
+	// Required is true when every parent object contains the field.
+	Required bool `json:"required"`
+	// Nullable is true when the field was seen holding null.
+	Nullable bool `json:"nullable,omitempty"`
+	// ElementTypes counts the types of array elements, for array fields.
+	ElementTypes map[string]int `json:"element_types,omitempty"`
+	// Samples holds a few distinct scalar values seen in the field.
+	Samples []interface{} `json:"samples,omitempty"`
+}
+
+type fieldStats struct {
+	count    int
+	types    map[string]int
+	elements map[string]int
+	samples  []interface{}
+}
+
+// schemaStats accumulates field statistics over sampled documents.
+type schemaStats struct {
+	fields map[string]*fieldStats
+	// objects counts the objects seen at each path; "" is the document root.
+	objects map[string]int
+}
+
+func newSchemaStats() *schemaStats {
+	return &schemaStats{fields: map[string]*fieldStats{}, objects: map[string]int{}}
+}
+
+// inferSchema walks docs and returns their fields sorted by path.
+func inferSchema(docs []map[string]interface{}) []FieldInfo {
+	stats := newSchemaStats()
+	for _, doc := range docs {
+		stats.walkObject("", doc, 0)
+	}
+	return stats.fieldInfos()
+}
+
+func (s *schemaStats) walkObject(path string, obj map[string]interface{}, depth int) {
+	s.objects[path]++
+	for key, value := range obj {
+		child := key
+		if path != "" {
+			child = path + "." + key
+		}
+		s.observe(child, value, depth+1)
+	}
+}
+
+func (s *schemaStats) observe(path string, value interface{}, depth int) {
+	f := s.fields[path]
+	if f == nil {
+		f = &fieldStats{types: map[string]int{}}
+		s.fields[path] = f
+	}
+	f.count++
+	typ := inferType(value)
+	f.types[typ]++
+	f.addSample(typ, value)
+
+	if depth >= maxSchemaDepth {
+		return
+	}
+	switch typ {
+	case "object":
+		if obj, ok := value.(map[string]interface{}); ok {
+			s.walkObject(path, obj, depth)
+		}
+	case "array":
+		if f.elements == nil {
+			f.elements = map[string]int{}
+		}
+		items := reflect.ValueOf(value)
+		for i := 0; i < items.Len(); i++ {
+			item := items.Index(i).Interface()
+			itemType := inferType(item)
+			f.elements[itemType]++
+			if obj, ok := item.(map[string]interface{}); ok && itemType == "object" {
+				s.walkObject(path+"[]", obj, depth)
+			}
+		}
+	}
+}
+
+func (f *fieldStats) addSample(typ string, value interface{}) {
+	if len(f.samples) >= maxSchemaSamples {
+		return
+	}
+	switch typ {
+	case "string":
+		if runes := []rune(value.(string)); len(runes) > maxSampleLength {
+			value = string(runes[:maxSampleLength]) + "…"
+		}
+	case "number", "bool":
+	case "time":
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(time.RFC3339Nano)
+		}
+	default:
+		return
+	}
+	for _, sample := range f.samples {
+		if sample == value {
+			return
+		}
+	}
+	f.samples = append(f.samples, value)
+}
+
+func (s *schemaStats) fieldInfos() []FieldInfo {
+	paths := make([]string, 0, len(s.fields))
+	for path := range s.fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	fields := make([]FieldInfo, 0, len(paths))
+	for _, path := range paths {
+		f := s.fields[path]
+		parents := s.objects[parentPath(path)]
+		info := FieldInfo{
+			Name:         path,
+			Type:         strings.Join(typesByFrequency(f.types), "|"),
+			Types:        f.types,
+			Count:        f.count,
+			Required:     f.count >= parents,
+			Nullable:     f.types["null"] > 0,
+			ElementTypes: f.elements,
+			Samples:      f.samples,
+		}
+		if parents > 0 {
+			info.Presence = float64(f.count) * 100 / float64(parents)
+		}
+		fields = append(fields, info)
+	}
+	return fields
+}
+
+// parentPath returns the path of the object holding the field at path.
+func parentPath(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// typesByFrequency returns the keys of counts, most frequent first.
+func typesByFrequency(counts map[string]int) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// inferType names the JSON or RethinkDB type of a decoded value. The driver
+// converts TIME, BINARY and GEOMETRY pseudo-types to time.Time, []byte and
+// types.Geometry; pseudo-types left in raw form are recognized by their
+// $reql_type$ field.
+func inferType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case time.Time:
+		return "time"
+	case []byte:
+		return "binary"
+	case types.Geometry:
+		return "geometry"
+	case map[string]interface{}:
+		if reqlType, ok := v["$reql_type$"].(string); ok {
+			return strings.ToLower(reqlType)
+		}
+		return "object"
+	}
+	rt := reflect.TypeOf(v)
+	switch rt.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Float64, reflect.Float32, reflect.Int, reflect.Int64, reflect.Int32, reflect.Uint, reflect.Uint64:
+		return "number"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map:
+		return "object"
+	default:
+		return rt.String()
+	}
+}
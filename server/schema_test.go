@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+	"gopkg.in/rethinkdb/rethinkdb-go.v6/types"
+)
+
+// ─── schema inference ───────────────────────────────────────────────────────
+
+func fieldByName(fields []FieldInfo, name string) *FieldInfo {
+	for i := range fields {
+		if fields[i].Name == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+func TestInferSchema_NestedAndMixed(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fields := inferSchema([]map[string]interface{}{
+		{
+			"id":      "1",
+			"score":   10.0,
+			"address": map[string]interface{}{"city": "Paris", "zip": "75001"},
+			"items":   []interface{}{map[string]interface{}{"sku": "a", "qty": 1.0}, map[string]interface{}{"sku": "b"}},
+			"created": created,
+		},
+		{
+			"id":      "2",
+			"score":   "n/a",
+			"address": map[string]interface{}{"city": "Lyon"},
+			"tags":    []interface{}{"x", 1.0},
+			"created": nil,
+		},
+	})
+
+	score := fieldByName(fields, "score")
+	if score == nil || score.Types["number"] != 1 || score.Types["string"] != 1 || score.Type != "number|string" {
+		t.Errorf("expected score to be number|string, got %+v", score)
+	}
+	if id := fieldByName(fields, "id"); id == nil || !id.Required || id.Presence != 100 {
+		t.Errorf("expected id to be required, got %+v", id)
+	}
+	if tags := fieldByName(fields, "tags"); tags == nil || tags.Required || tags.Presence != 50 {
+		t.Errorf("expected tags to be optional at 50%%, got %+v", tags)
+	} else if tags.ElementTypes["string"] != 1 || tags.ElementTypes["number"] != 1 {
+		t.Errorf("unexpected element types: %v", tags.ElementTypes)
+	}
+	if city := fieldByName(fields, "address.city"); city == nil || !city.Required {
+		t.Errorf("expected address.city to be required, got %+v", city)
+	}
+	if zip := fieldByName(fields, "address.zip"); zip == nil || zip.Required || zip.Presence != 50 {
+		t.Errorf("expected address.zip to be optional, got %+v", zip)
+	}
+	if qty := fieldByName(fields, "items[].qty"); qty == nil || qty.Required || qty.Count != 1 {
+		t.Errorf("expected items[].qty to be optional, got %+v", qty)
+	}
+	if sku := fieldByName(fields, "items[].sku"); sku == nil || !sku.Required || len(sku.Samples) != 2 {
+		t.Errorf("expected items[].sku to be required with 2 samples, got %+v", sku)
+	}
+	if c := fieldByName(fields, "created"); c == nil || c.Types["time"] != 1 || !c.Nullable {
+		t.Errorf("expected created to be a nullable time, got %+v", c)
+	} else if c.Samples[0] != "2024-01-02T03:04:05Z" {
+		t.Errorf("unexpected time sample: %v", c.Samples)
+	}
+}
+
+func TestInferType_PseudoTypes(t *testing.T) {
+	tests := map[string]interface{}{
+		"time":     time.Now(),
+		"binary":   []byte("abc"),
+		"geometry": types.Geometry{Type: "Point", Point: types.Point{Lon: 1, Lat: 2}},
+		"object":   map[string]interface{}{"a": 1},
+		"array":    []interface{}{1},
+		"null":     nil,
+	}
+	for want, value := range tests {
+		if got := inferType(value); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+	raw := map[string]interface{}{"$reql_type$": "TIME", "epoch_time": 1.0, "timezone": "+00:00"}
+	if got := inferType(raw); got != "time" {
+		t.Errorf("expected a raw TIME to be time, got %s", got)
+	}
+}
+
+func TestSchemaInspector_NestedFields(t *testing.T) {
+	srv := newTestServer()
+	table := "mcp_test_schema_table"
+	r.DB(testDB).TableCreate(table).RunWrite(testSession)
+	defer r.DB(testDB).TableDrop(table).RunWrite(testSession)
+	r.DB(testDB).Table(table).Insert([]map[string]interface{}{
+		{"id": "1", "profile": map[string]interface{}{"email": "a@example.com"}, "created": r.Now()},
+		{"id": "2", "profile": map[string]interface{}{"email": "b@example.com", "phone": "555"}},
+	}).RunWrite(testSession)
+
+	_, output, err := srv.SchemaInspector(context.Background(), &mcp.CallToolRequest{}, SchemaInspectorInput{
+		Database: testDB,
+		Table:    table,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if email := fieldByName(output.Fields, "profile.email"); email == nil || !email.Required {
+		t.Errorf("expected profile.email to be required, got %+v", email)
+	}
+	if phone := fieldByName(output.Fields, "profile.phone"); phone == nil || phone.Required {
+		t.Errorf("expected profile.phone to be optional, got %+v", phone)
+	}
+	if created := fieldByName(output.Fields, "created"); created == nil || created.Type != "time" {
+		t.Errorf("expected created to be a time, got %+v", created)
+	}
+}
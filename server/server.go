@@ -6,7 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"reflect"
+	"sync"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -15,12 +15,145 @@ import (
 
 // RethinkDBServer holds the session and provides MCP tool handlers.
 type RethinkDBServer struct {
-	session *r.Session
+	session   *r.Session
+	readOnly  bool
+	policy    *Policy
+	reqlPerms ReQLPermissions
+	timeouts  Timeouts
+	admin     bool
+	history   queryHistory
+
+	// schemas and schemaTable ("database.table") supply the JSON Schemas
+	// write_data validates documents against.
+	schemas     *TableSchemas
+	schemaTable string
+
+	// maxAffected caps how many documents a delete or an update by keys or
+	// filter may touch without confirm_count; 0 means no limit.
+	maxAffected int
+
+	// audit records every write_data call when set.
+	audit auditSink
+
+	// maxWatches caps the changefeeds one session may have open; 0 means
+	// no limit.
+	maxWatches int
+
+	// mcpServer is set by RegisterTools; watches publish resources on it.
+	mcpServer       *mcp.Server
+	watchMu         sync.Mutex
+	watches         map[string]*watch
+	watchedSessions map[*mcp.ServerSession]bool
+
+	confirmMu     sync.Mutex
+	confirmations map[string]pendingDrop
+}
+
+// Option configures optional behaviour of a RethinkDBServer.
+type Option func(*RethinkDBServer)
+
+// WithReadOnly puts the server in read-only mode: mutating tools are not
+// registered and every handler rejects queries that contain a write term.
+func WithReadOnly(readOnly bool) Option {
+	return func(s *RethinkDBServer) {
+		s.readOnly = readOnly
+	}
+}
+
+// WithPolicy restricts the databases and tables the tools may access. A nil
+// policy allows everything.
+func WithPolicy(policy *Policy) Option {
+	return func(s *RethinkDBServer) {
+		s.policy = policy
+	}
+}
+
+// WithReQLPermissions enables r.js, r.http or write terms in run_reql, which
+// are rejected by default.
+func WithReQLPermissions(perms ReQLPermissions) Option {
+	return func(s *RethinkDBServer) {
+		s.reqlPerms = perms
+	}
+}
+
+// WithTimeouts sets the default and per-tool query timeouts. Calls may
+// shorten them, but not extend them, with timeout_ms.
+func WithTimeouts(timeouts Timeouts) Option {
+	return func(s *RethinkDBServer) {
+		s.timeouts = timeouts
+	}
+}
+
+// WithAdmin enables the database and table administration tools. They stay
+// disabled in read-only mode.
+func WithAdmin(admin bool) Option {
+	return func(s *RethinkDBServer) {
+		s.admin = admin
+	}
+}
+
+// WithTableSchemas makes write_data validate documents written to the
+// tables in schemas against their JSON Schema.
+func WithTableSchemas(schemas *TableSchemas) Option {
+	return func(s *RethinkDBServer) {
+		s.schemas = schemas
+	}
+}
+
+// WithSchemaTable makes write_data look up JSON Schemas in the RethinkDB
+// table named "database.table". Each document's id is the "database.table"
+// it applies to and its schema field holds the schema.
+func WithSchemaTable(name string) Option {
+	return func(s *RethinkDBServer) {
+		s.schemaTable = name
+	}
+}
+
+// WithMaxAffected makes write_data refuse deletes and updates by keys or
+// filter that match more than n documents, unless the call confirms the
+// exact count. Zero disables the limit.
+func WithMaxAffected(n int) Option {
+	return func(s *RethinkDBServer) {
+		s.maxAffected = n
+	}
+}
+
+// WithMaxWatches limits how many changefeeds a session may have open at
+// once with watch_table. Zero disables the limit.
+func WithMaxWatches(n int) Option {
+	return func(s *RethinkDBServer) {
+		s.maxWatches = n
+	}
+}
+
+// WithAuditFile makes write_data record every call in the JSON lines audit
+// file, which list_audit_log reads back.
+func WithAuditFile(file *AuditFile) Option {
+	return func(s *RethinkDBServer) {
+		if file != nil {
+			s.audit = file
+		}
+	}
+}
+
+// WithAuditTable makes write_data record every call in the RethinkDB table
+// named "database.table", which list_audit_log reads back. write_data
+// refuses to write to the table itself.
+func WithAuditTable(name string) Option {
+	return func(s *RethinkDBServer) {
+		if name != "" {
+			s.audit = &auditTable{session: s.session, name: name}
+		}
+	}
 }
 
 // NewRethinkDBServer creates a new server with the given RethinkDB session.
-func NewRethinkDBServer(session *r.Session) *RethinkDBServer {
-	return &RethinkDBServer{session: session}
+func NewRethinkDBServer(session *r.Session, opts ...Option) *RethinkDBServer {
+	s := &RethinkDBServer{session: session}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // ─── Input/Output structs ────────────────────────────────────────────────────
@@ -32,7 +165,8 @@ type ListDatabasesOutput struct {
 }
 
 type ListTablesInput struct {
-	Database string `json:"database" jsonschema:"The database name to list tables from"`
+	Database  string `json:"database" jsonschema:"The database name to list tables from"`
+	TimeoutMs int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
 }
 
 type ListTablesOutput struct {
@@ -41,11 +175,14 @@ type ListTablesOutput struct {
 }
 
 type QueryTableInput struct {
-	Database string         `json:"database" jsonschema:"The database name"`
-	Table    string         `json:"table" jsonschema:"The table name"`
-	Filter   map[string]any `json:"filter,omitempty" jsonschema:"Optional filter object for the query"`
-	Limit    int            `json:"limit,omitempty" jsonschema:"Maximum number of results (default 100, max 1000)"`
-	OrderBy  string         `json:"order_by,omitempty" jsonschema:"Optional field to order results by"`
+	Database  string         `json:"database" jsonschema:"The database name"`
+	Table     string         `json:"table" jsonschema:"The table name"`
+	Filter    map[string]any `json:"filter,omitempty" jsonschema:"Optional filter object. Plain values match by equality; operators $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $regex, $exists, $not, $and and $or are supported, and keys may be dotted paths like address.city"`
+	Limit     int            `json:"limit,omitempty" jsonschema:"Maximum number of results (default 100, max 1000)"`
+	OrderBy   string         `json:"order_by,omitempty" jsonschema:"Optional field to order results by"`
+	Cursor    string         `json:"cursor,omitempty" jsonschema:"Opaque next_cursor from a previous call with the same filter and order_by, to fetch the next page"`
+	TimeoutMs int            `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+	Profile   bool           `json:"profile,omitempty" jsonschema:"Run the query with the ReQL profiler and return the profile tree and a summary"`
 }
 
 type QueryTableOutput struct {
@@ -53,12 +190,20 @@ type QueryTableOutput struct {
 	Table           string  `json:"table"`
 	Count           int     `json:"count"`
 	Results         []any   `json:"results"`
+	NextCursor      string  `json:"next_cursor,omitempty"`
 	ExecutionTimeMs float64 `json:"execution_time_ms"`
+	// Index is the secondary or primary index the query read through, and
+	// IndexUsage how: "get_all" for an equality filter, "order_by" for an
+	// indexed order. Both are empty for a table scan.
+	Index      string        `json:"index,omitempty"`
+	IndexUsage string        `json:"index_usage,omitempty"`
+	Profile    *QueryProfile `json:"profile,omitempty"`
 }
 
 type TableInfoInput struct {
-	Database string `json:"database" jsonschema:"The database name"`
-	Table    string `json:"table" jsonschema:"The table name"`
+	Database  string `json:"database" jsonschema:"The database name"`
+	Table     string `json:"table" jsonschema:"The table name"`
+	TimeoutMs int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
 }
 
 type TableInfoOutput struct {
@@ -73,8 +218,30 @@ type TableInfoOutput struct {
 type WriteDataInput struct {
 	Database  string          `json:"database" jsonschema:"The database name"`
 	Table     string          `json:"table" jsonschema:"The table name"`
-	Data      json.RawMessage `json:"data" jsonschema:"The data to write. For insert/update/upsert: a document or array of documents. For delete: a document with the primary key field, or a filter object to match multiple documents."`
-	Operation string          `json:"operation,omitempty" jsonschema:"The write operation: insert (default), update, upsert, or delete"`
+	Data      json.RawMessage `json:"data" jsonschema:"The data to write. For insert/update/upsert: a document or array of documents. For update: documents carrying their primary key, or one update document, optionally with update operators ($set, $inc, $unset, $push, $pull, $currentTime), applied to keys or filter. For compare_and_set: a single document carrying its primary key. For delete: a document with only the primary key field, an array of primary keys, or a filter object to match multiple documents."`
+	Operation string          `json:"operation,omitempty" jsonschema:"The write operation: insert (default), update, upsert, delete, or compare_and_set"`
+	DryRun    bool            `json:"dry_run,omitempty" jsonschema:"Report what the write would do, with a preview of affected documents, without writing anything"`
+	// ReturnChanges asks RethinkDB for the old and new value of every
+	// changed document; MaxChanges bounds how many are returned.
+	ReturnChanges bool `json:"return_changes,omitempty" jsonschema:"Return the old and new value of each changed document"`
+	MaxChanges    int  `json:"max_changes,omitempty" jsonschema:"Maximum number of changes to return when return_changes is set (default 20, max 1000)"`
+	// VersionField, ExpectedVersion and ExpectedHash form the precondition
+	// of a compare_and_set.
+	VersionField    string          `json:"version_field,omitempty" jsonschema:"For compare_and_set: the field holding the document version (default version)"`
+	ExpectedVersion json.RawMessage `json:"expected_version,omitempty" jsonschema:"For compare_and_set: the value the version field must have for the update to apply"`
+	ExpectedHash    string          `json:"expected_hash,omitempty" jsonschema:"For compare_and_set: the hash the stored document must have, as returned in a previous conflict"`
+	// Keys and Filter select the documents an update document applies to,
+	// instead of the primary keys in data.
+	Keys   []any          `json:"keys,omitempty" jsonschema:"For update: primary keys of the documents to apply the update document in data to"`
+	Filter map[string]any `json:"filter,omitempty" jsonschema:"For update: filter selecting the documents to apply the update document in data to, using the same operator syntax as query_table"`
+	// ConfirmCount lets a delete or update touch more documents than the
+	// server's limit, if it equals the number of matched documents.
+	ConfirmCount int `json:"confirm_count,omitempty" jsonschema:"The exact number of documents a delete or update by keys or filter matches, required when it exceeds the server's limit"`
+	TimeoutMs    int `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+
+	// auditChanges asks for changes for the audit log even when the caller
+	// did not set ReturnChanges.
+	auditChanges bool
 }
 
 type WriteDataOutput struct {
@@ -87,6 +254,33 @@ type WriteDataOutput struct {
 	Deleted    int    `json:"deleted"`
 	Errors     int    `json:"errors"`
 	FirstError string `json:"first_error,omitempty"`
+	// ValidationErrors lists the documents that failed the table's JSON
+	// Schema. When it is non-empty nothing was written.
+	ValidationErrors []ValidationError `json:"validation_errors,omitempty"`
+	// DryRun is true when nothing was written and the counts are a
+	// prediction. Preview then samples the affected documents.
+	DryRun  bool           `json:"dry_run,omitempty"`
+	Preview []WritePreview `json:"preview,omitempty"`
+	// Conflict is set when a compare_and_set precondition failed and nothing
+	// was written.
+	Conflict *WriteConflict `json:"conflict,omitempty"`
+	// Matched is the number of documents a refused delete or update matched;
+	// pass it back as confirm_count to proceed.
+	Matched int `json:"matched,omitempty"`
+	// NotFound lists the primary keys given to an update or delete that
+	// matched no document.
+	NotFound []interface{} `json:"not_found,omitempty"`
+	// GeneratedKeys are the primary keys RethinkDB generated for inserted
+	// documents that had none.
+	GeneratedKeys []string `json:"generated_keys,omitempty"`
+	// Changes holds the old and new values of changed documents when
+	// return_changes is set, truncated to max_changes.
+	Changes          []WriteChange `json:"changes,omitempty"`
+	ChangesTruncated bool          `json:"changes_truncated,omitempty"`
+	Warnings         []string      `json:"warnings,omitempty"`
+
+	// changes holds every change, untruncated, for the audit log.
+	changes []WriteChange
 }
 
 type AggregateInput struct {
@@ -94,16 +288,19 @@ type AggregateInput struct {
 	Table            string         `json:"table" jsonschema:"The table name"`
 	Operation        string         `json:"operation" jsonschema:"Aggregation operation: count, sum, avg, min, max, or group"`
 	Field            string         `json:"field,omitempty" jsonschema:"Field to aggregate on (required for sum, avg, min, max, group)"`
-	Filter           map[string]any `json:"filter,omitempty" jsonschema:"Optional filter to apply before aggregation"`
+	Filter           map[string]any `json:"filter,omitempty" jsonschema:"Optional filter to apply before aggregation, using the same operator syntax as query_table"`
 	GroupAggregation string         `json:"group_aggregation,omitempty" jsonschema:"When operation is group, apply this aggregation per group: count, sum, avg, min, max"`
+	TimeoutMs        int            `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+	Profile          bool           `json:"profile,omitempty" jsonschema:"Run the query with the ReQL profiler and return the profile tree and a summary"`
 }
 
 type AggregateOutput struct {
-	Database  string `json:"database"`
-	Table     string `json:"table"`
-	Operation string `json:"operation"`
-	Field     string `json:"field,omitempty"`
-	Value     any    `json:"value"`
+	Database  string        `json:"database"`
+	Table     string        `json:"table"`
+	Operation string        `json:"operation"`
+	Field     string        `json:"field,omitempty"`
+	Value     any           `json:"value"`
+	Profile   *QueryProfile `json:"profile,omitempty"`
 }
 
 type AdvancedQueryInput struct {
@@ -119,40 +316,51 @@ type AdvancedQueryInput struct {
 	ContainsValue json.RawMessage `json:"contains_value,omitempty" jsonschema:"Value to look for in the array field (for contains)"`
 	MapExpr       map[string]any  `json:"map_expr,omitempty" jsonschema:"Object with field names set to true to pluck from each document (for map)"`
 	Limit         int             `json:"limit,omitempty" jsonschema:"Maximum number of results (default 100, max 1000)"`
+	Cursor        string          `json:"cursor,omitempty" jsonschema:"Opaque next_cursor from a previous call with the same arguments, to fetch the next page"`
+	TimeoutMs     int             `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+	Profile       bool            `json:"profile,omitempty" jsonschema:"Run the query with the ReQL profiler and return the profile tree and a summary"`
 }
 
 type AdvancedQueryOutput struct {
-	Database  string `json:"database"`
-	Table     string `json:"table"`
-	Operation string `json:"operation"`
-	Count     int    `json:"count"`
-	Results   []any  `json:"results"`
+	Database   string        `json:"database"`
+	Table      string        `json:"table"`
+	Operation  string        `json:"operation"`
+	Count      int           `json:"count"`
+	Results    []any         `json:"results"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	Note       string        `json:"note,omitempty"`
+	Profile    *QueryProfile `json:"profile,omitempty"`
 }
 
 type SchemaInspectorInput struct {
 	Database   string `json:"database" jsonschema:"The database name"`
 	Table      string `json:"table" jsonschema:"The table name"`
 	SampleSize int    `json:"sample_size,omitempty" jsonschema:"Number of documents to sample for schema inference (default 100)"`
-}
-
-type FieldInfo struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
+	Strategy   string `json:"strategy,omitempty" jsonschema:"Sampling strategy: first (default), random (reads the whole table), recent (newest by time_index) or stratified (evenly across primary key ranges)"`
+	TimeIndex  string `json:"time_index,omitempty" jsonschema:"Secondary index ordering documents by time, for the recent strategy (default: an index named created_at, updated_at or timestamp)"`
+	TimeoutMs  int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
 }
 
 type SchemaInspectorOutput struct {
-	Database   string      `json:"database"`
-	Table      string      `json:"table"`
-	PrimaryKey string      `json:"primary_key"`
-	Indexes    []string    `json:"indexes"`
-	DocCount   int         `json:"doc_count"`
-	SampleSize int         `json:"sample_size"`
+	Database   string   `json:"database"`
+	Table      string   `json:"table"`
+	PrimaryKey string   `json:"primary_key"`
+	Indexes    []string `json:"indexes"`
+	DocCount   int      `json:"doc_count"`
+	SampleSize int      `json:"sample_size"`
+	Strategy   string   `json:"strategy"`
+	Sampled    int      `json:"sampled"`
+	// Confidence is the probability that a field present in at least 1% of
+	// documents was seen, assuming a random sample.
+	Confidence float64     `json:"confidence"`
+	Note       string      `json:"note,omitempty"`
 	Fields     []FieldInfo `json:"fields"`
 }
 
 type IndexInfoInput struct {
-	Database string `json:"database" jsonschema:"The database name"`
-	Table    string `json:"table" jsonschema:"The table name"`
+	Database  string `json:"database" jsonschema:"The database name"`
+	Table     string `json:"table" jsonschema:"The table name"`
+	TimeoutMs int    `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
 }
 
 type IndexDetail struct {
@@ -172,18 +380,29 @@ type IndexInfoOutput struct {
 // ─── Tool handlers ───────────────────────────────────────────────────────────
 
 func (s *RethinkDBServer) ListDatabases(ctx context.Context, req *mcp.CallToolRequest, input EmptyInput) (*mcp.CallToolResult, ListDatabasesOutput, error) {
-	cursor, err := r.DBList().Run(s.session)
+	ctx, cancel := s.withTimeout(ctx, "list_databases", 0)
+	defer cancel()
+	what := "list_databases"
+
+	cursor, err := r.DBList().Run(s.session, r.RunOpts{Context: ctx})
 	if err != nil {
-		return nil, ListDatabasesOutput{}, fmt.Errorf("failed to list databases: %w", err)
+		return nil, ListDatabasesOutput{}, queryError(ctx, what, fmt.Errorf("failed to list databases: %w", err))
 	}
 	defer cursor.Close()
 
 	var databases []string
 	if err := cursor.All(&databases); err != nil {
-		return nil, ListDatabasesOutput{}, fmt.Errorf("failed to read databases: %w", err)
+		return nil, ListDatabasesOutput{}, queryError(ctx, what, fmt.Errorf("failed to read databases: %w", err))
 	}
 
-	return nil, ListDatabasesOutput{Databases: databases}, nil
+	allowed := make([]string, 0, len(databases))
+	for _, db := range databases {
+		if s.policy.DatabaseAllowed(db) {
+			allowed = append(allowed, db)
+		}
+	}
+
+	return nil, ListDatabasesOutput{Databases: allowed}, nil
 }
 
 func (s *RethinkDBServer) ListTables(ctx context.Context, req *mcp.CallToolRequest, input ListTablesInput) (*mcp.CallToolResult, ListTablesOutput, error) {
@@ -191,20 +410,32 @@ func (s *RethinkDBServer) ListTables(ctx context.Context, req *mcp.CallToolReque
 		return nil, ListTablesOutput{}, fmt.Errorf("database name is required")
 	}
 
-	cursor, err := r.DB(input.Database).TableList().Run(s.session)
+	if err := s.checkDatabase(input.Database); err != nil {
+		return nil, ListTablesOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "list_tables", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("list_tables on %s", input.Database)
+
+	cursor, err := r.DB(input.Database).TableList().Run(s.session, r.RunOpts{Context: ctx})
 	if err != nil {
-		return nil, ListTablesOutput{}, fmt.Errorf("failed to list tables: %w", err)
+		return nil, ListTablesOutput{}, queryError(ctx, what, fmt.Errorf("failed to list tables: %w", err))
 	}
 	defer cursor.Close()
 
 	var tables []string
 	if err := cursor.All(&tables); err != nil {
-		return nil, ListTablesOutput{}, fmt.Errorf("failed to read tables: %w", err)
+		return nil, ListTablesOutput{}, queryError(ctx, what, fmt.Errorf("failed to read tables: %w", err))
 	}
 
-	if tables == nil {
-		tables = []string{}
+	allowed := make([]string, 0, len(tables))
+	for _, table := range tables {
+		if s.policy.TableAllowed(input.Database, table) {
+			allowed = append(allowed, table)
+		}
 	}
+	tables = allowed
 
 	return nil, ListTablesOutput{Database: input.Database, Tables: tables}, nil
 }
@@ -214,47 +445,123 @@ func (s *RethinkDBServer) QueryTable(ctx context.Context, req *mcp.CallToolReque
 		return nil, QueryTableOutput{}, fmt.Errorf("database and table names are required")
 	}
 
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, QueryTableOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "query_table", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("query_table on %s.%s", input.Database, input.Table)
+
 	start := time.Now()
 
-	limit := input.Limit
-	if limit <= 0 {
-		limit = 100
+	limit := applyLimit(input.Limit)
+
+	filter, err := compileFilter(input.Filter)
+	if err != nil {
+		return nil, QueryTableOutput{}, fmt.Errorf("invalid filter: %w", err)
 	}
-	if limit > 1000 {
-		limit = 1000
+
+	pk, err := s.primaryKey(ctx, input.Database, input.Table)
+	if err != nil {
+		return nil, QueryTableOutput{}, queryError(ctx, what, err)
 	}
 
-	query := r.DB(input.Database).Table(input.Table)
+	var plan queryPlan
+	if len(input.Filter) > 0 || (input.OrderBy != "" && input.OrderBy != pk) {
+		indexes, err := s.fieldIndexes(ctx, input.Database, input.Table)
+		if err != nil {
+			return nil, QueryTableOutput{}, queryError(ctx, what, err)
+		}
+		plan = planQuery(input.Filter, input.OrderBy, pk, indexes)
+	}
 
-	if len(input.Filter) > 0 {
-		query = query.Filter(input.Filter)
+	// The plan is part of the shape so a cursor is not resumed with a
+	// different plan after an index is created or dropped.
+	shape := queryShape("query_table", input.Database, input.Table, input.Filter, input.OrderBy, plan.Index, plan.Usage)
+	after, err := decodeCursor(input.Cursor, shape)
+	if err != nil {
+		return nil, QueryTableOutput{}, err
 	}
 
-	if input.OrderBy != "" {
-		query = query.OrderBy(input.OrderBy)
+	table := r.DB(input.Database).Table(input.Table)
+	var query r.Term
+	var index, field string
+
+	switch {
+	case plan.Usage == indexGetAll:
+		query = table.GetAllByIndex(plan.Index, plan.Value)
+		if filter != nil {
+			query = query.Filter(filter)
+		}
+		field = input.OrderBy
+		if field == "" {
+			field = pk
+		}
+		query = pageByField(query, field, pk, after)
+	case plan.Usage == indexOrderBy:
+		index, field = plan.Index, plan.Index
+		query = pageByIndex(table, index, field, pk, r.MinVal, r.MaxVal, after)
+		if filter != nil {
+			query = query.Filter(filter)
+		}
+	case input.OrderBy == "" || input.OrderBy == pk:
+		// Page through the primary key index so each page resumes with
+		// Between instead of re-reading skipped documents.
+		index = pk
+		query = pageByIndex(table, pk, pk, pk, r.MinVal, r.MaxVal, after)
+		if filter != nil {
+			query = query.Filter(filter)
+		}
+	default:
+		query = table
+		if filter != nil {
+			query = query.Filter(filter)
+		}
+		field = input.OrderBy
+		query = pageByField(query, field, pk, after)
 	}
 
-	query = query.Limit(limit)
+	// Fetch one extra document to learn whether another page exists.
+	query = query.Limit(limit + 1)
 
-	cursor, err := query.Run(s.session)
+	if err := s.checkReadOnly(query); err != nil {
+		return nil, QueryTableOutput{}, err
+	}
+	what = describeQuery(what, query)
+
+	cursor, err := query.Run(s.session, r.RunOpts{Context: ctx, Profile: input.Profile})
 	if err != nil {
-		return nil, QueryTableOutput{}, fmt.Errorf("failed to execute query: %w", err)
+		return nil, QueryTableOutput{}, queryError(ctx, what, fmt.Errorf("failed to execute query: %w", err))
 	}
 	defer cursor.Close()
 
 	var results []interface{}
 	if err := cursor.All(&results); err != nil {
-		return nil, QueryTableOutput{}, fmt.Errorf("failed to read results: %w", err)
+		return nil, QueryTableOutput{}, queryError(ctx, what, fmt.Errorf("failed to read results: %w", err))
+	}
+
+	var next string
+	if len(results) > limit {
+		results = results[:limit]
+		if next, err = nextCursor(results[limit-1], shape, index, field, pk); err != nil {
+			return nil, QueryTableOutput{}, err
+		}
 	}
 
 	elapsed := time.Since(start)
+	s.history.record("query_table", input.Database, input.Table, input.Filter, input.OrderBy)
 
 	return nil, QueryTableOutput{
 		Database:        input.Database,
 		Table:           input.Table,
 		Count:           len(results),
 		Results:         results,
+		NextCursor:      next,
 		ExecutionTimeMs: float64(elapsed.Microseconds()) / 1000.0,
+		Index:           plan.Index,
+		IndexUsage:      plan.Usage,
+		Profile:         profileOf(cursor, input.Profile),
 	}, nil
 }
 
@@ -263,27 +570,35 @@ func (s *RethinkDBServer) TableInfo(ctx context.Context, req *mcp.CallToolReques
 		return nil, TableInfoOutput{}, fmt.Errorf("database and table names are required")
 	}
 
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, TableInfoOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "table_info", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("table_info on %s.%s", input.Database, input.Table)
+
 	output := TableInfoOutput{
 		Database: input.Database,
 		Table:    input.Table,
 	}
 
-	cursor, err := r.DB(input.Database).Table(input.Table).Info().Run(s.session)
+	cursor, err := r.DB(input.Database).Table(input.Table).Info().Run(s.session, r.RunOpts{Context: ctx})
 	if err != nil {
-		return nil, TableInfoOutput{}, fmt.Errorf("failed to get table info: %w", err)
+		return nil, TableInfoOutput{}, queryError(ctx, what, fmt.Errorf("failed to get table info: %w", err))
 	}
 	defer cursor.Close()
 
 	var info map[string]interface{}
 	if err := cursor.One(&info); err != nil {
-		return nil, TableInfoOutput{}, fmt.Errorf("failed to read table info: %w", err)
+		return nil, TableInfoOutput{}, queryError(ctx, what, fmt.Errorf("failed to read table info: %w", err))
 	}
 
 	if pk, ok := info["primary_key"].(string); ok {
 		output.PrimaryKey = pk
 	}
 
-	indexCursor, err := r.DB(input.Database).Table(input.Table).IndexList().Run(s.session)
+	indexCursor, err := r.DB(input.Database).Table(input.Table).IndexList().Run(s.session, r.RunOpts{Context: ctx})
 	if err == nil {
 		defer indexCursor.Close()
 		var indexes []string
@@ -295,7 +610,7 @@ func (s *RethinkDBServer) TableInfo(ctx context.Context, req *mcp.CallToolReques
 		output.Indexes = []string{}
 	}
 
-	countCursor, err := r.DB(input.Database).Table(input.Table).Count().Run(s.session)
+	countCursor, err := r.DB(input.Database).Table(input.Table).Count().Run(s.session, r.RunOpts{Context: ctx})
 	if err == nil {
 		defer countCursor.Close()
 		var count int
@@ -308,10 +623,37 @@ func (s *RethinkDBServer) TableInfo(ctx context.Context, req *mcp.CallToolReques
 }
 
 func (s *RethinkDBServer) WriteData(ctx context.Context, req *mcp.CallToolRequest, input WriteDataInput) (*mcp.CallToolResult, WriteDataOutput, error) {
+	if s.audit == nil {
+		return s.writeData(ctx, input)
+	}
+
+	input.auditChanges = true
+	res, output, err := s.writeData(ctx, input)
+	// Record the call even if the client has gone away.
+	s.recordWrite(context.WithoutCancel(ctx), req, input, &output, err)
+	return res, output, err
+}
+
+func (s *RethinkDBServer) writeData(ctx context.Context, input WriteDataInput) (*mcp.CallToolResult, WriteDataOutput, error) {
+	if s.readOnly {
+		return nil, WriteDataOutput{}, fmt.Errorf("%w: write_data is disabled", ErrReadOnly)
+	}
+
 	if input.Database == "" || input.Table == "" {
 		return nil, WriteDataOutput{}, fmt.Errorf("database and table names are required")
 	}
 
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, WriteDataOutput{}, err
+	}
+	if err := s.checkReservedTables(input.Database, input.Table); err != nil {
+		return nil, WriteDataOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "write_data", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("write_data on %s.%s", input.Database, input.Table)
+
 	if len(input.Data) == 0 {
 		return nil, WriteDataOutput{}, fmt.Errorf("data is required")
 	}
@@ -323,10 +665,13 @@ func (s *RethinkDBServer) WriteData(ctx context.Context, req *mcp.CallToolReques
 
 	// Validate operation
 	switch operation {
-	case "insert", "update", "upsert", "delete":
+	case "insert", "update", "upsert", "delete", "compare_and_set":
 		// valid
 	default:
-		return nil, WriteDataOutput{}, fmt.Errorf("invalid operation %q: must be one of insert, update, upsert, delete", operation)
+		return nil, WriteDataOutput{}, fmt.Errorf("invalid operation %q: must be one of insert, update, upsert, delete, compare_and_set", operation)
+	}
+	if (input.Keys != nil || input.Filter != nil) && operation != "update" {
+		return nil, WriteDataOutput{}, fmt.Errorf("keys and filter only apply to update; %s takes its documents or keys in data", operation)
 	}
 
 	// Parse the data - could be a single document or an array
@@ -335,59 +680,118 @@ func (s *RethinkDBServer) WriteData(ctx context.Context, req *mcp.CallToolReques
 		return nil, WriteDataOutput{}, fmt.Errorf("failed to parse data: %w", err)
 	}
 
+	if operation == "update" && updatesWhere(input, data) {
+		return s.updateWhere(ctx, what, input, data)
+	}
+
+	if operation != "delete" {
+		docs := documentList(data)
+		failures, err := s.validateDocuments(ctx, input.Database, input.Table, operation, docs)
+		if err != nil {
+			return nil, WriteDataOutput{}, queryError(ctx, what, err)
+		}
+		if len(failures) > 0 {
+			return &mcp.CallToolResult{IsError: true}, WriteDataOutput{
+				Database:         input.Database,
+				Table:            input.Table,
+				Operation:        operation,
+				Errors:           len(failures),
+				FirstError:       fmt.Sprintf("%d of %d documents failed schema validation; nothing was written", len(failures), len(docs)),
+				ValidationErrors: failures,
+				DryRun:           input.DryRun,
+			}, nil
+		}
+	}
+
+	if operation == "compare_and_set" {
+		return s.compareAndSet(ctx, what, input, data)
+	}
+
+	if input.DryRun {
+		output, err := s.previewWrite(ctx, input.Database, input.Table, operation, data)
+		if err != nil {
+			return nil, WriteDataOutput{}, queryError(ctx, what, err)
+		}
+		return nil, output, nil
+	}
+
 	table := r.DB(input.Database).Table(input.Table)
-	var writeResp r.WriteResponse
-	var err error
+	var query r.Term
+	var keys []interface{}
 
 	switch operation {
 	case "insert":
-		writeResp, err = table.Insert(data).RunWrite(s.session)
+		query = table.Insert(data, r.InsertOpts{ReturnChanges: input.wantsChanges()})
 
 	case "update":
-		// For update: if data is a single doc with a primary key, use Get().Update()
-		// If it's an array, use insert with conflict: "update"
-		writeResp, err = table.Insert(data, r.InsertOpts{Conflict: "update"}).RunWrite(s.session)
+		// Update each document by primary key, merging in its fields.
+		// Documents that do not exist are reported, not inserted.
+		pk, err := s.primaryKey(ctx, input.Database, input.Table)
+		if err != nil {
+			return nil, WriteDataOutput{}, queryError(ctx, what, err)
+		}
+		docs := documentList(data)
+		if keys, err = documentKeys(pk, docs); err != nil {
+			return nil, WriteDataOutput{}, fmt.Errorf("invalid update: %w", err)
+		}
+		query = updateQuery(table, pk, docs, keys, r.UpdateOpts{ReturnChanges: input.wantsChanges()})
 
 	case "upsert":
 		// Upsert: insert with conflict: "replace"
-		writeResp, err = table.Insert(data, r.InsertOpts{Conflict: "replace"}).RunWrite(s.session)
+		query = table.Insert(data, r.InsertOpts{Conflict: "replace", ReturnChanges: input.wantsChanges()})
 
 	case "delete":
-		// For delete: use the data as a filter to find and delete matching documents
-		// If the data has an "id" field and nothing else, delete by primary key
-		docMap, isMap := data.(map[string]interface{})
-		if isMap {
-			if id, hasID := docMap["id"]; hasID && len(docMap) == 1 {
-				// Delete by primary key
-				writeResp, err = table.Get(id).Delete().RunWrite(s.session)
-			} else {
-				// Delete by filter
-				writeResp, err = table.Filter(data).Delete().RunWrite(s.session)
-			}
-		} else {
-			// Array of IDs or documents - try filter
-			writeResp, err = table.Filter(data).Delete().RunWrite(s.session)
+		// Delete by primary key, a list of primary keys, or a filter
+		pk, err := s.primaryKey(ctx, input.Database, input.Table)
+		if err != nil {
+			return nil, WriteDataOutput{}, queryError(ctx, what, err)
+		}
+		selection, deleteKeys, err := deleteSelection(table, pk, data)
+		if err != nil {
+			return nil, WriteDataOutput{}, err
+		}
+		keys = deleteKeys
+		matched, refusal, err := s.checkAffected(ctx, selection, operation, input.ConfirmCount)
+		if err != nil {
+			return nil, WriteDataOutput{}, queryError(ctx, what, err)
+		}
+		if refusal != "" {
+			return &mcp.CallToolResult{IsError: true}, WriteDataOutput{
+				Database:   input.Database,
+				Table:      input.Table,
+				Operation:  operation,
+				FirstError: refusal,
+				Matched:    matched,
+			}, nil
 		}
+		query = selection.Delete(r.DeleteOpts{ReturnChanges: input.wantsChanges()})
+	}
+
+	// Keys are looked up before writing so that the documents an update
+	// or delete could not find can be reported.
+	notFound, err := s.missingKeys(ctx, input.Database, input.Table, keys)
+	if err != nil {
+		return nil, WriteDataOutput{}, queryError(ctx, what, err)
 	}
 
+	result, err := s.runWrite(ctx, query)
 	if err != nil {
-		return nil, WriteDataOutput{}, fmt.Errorf("failed to execute %s: %w", operation, err)
+		return nil, WriteDataOutput{}, queryError(ctx, what, fmt.Errorf("failed to execute %s: %w", operation, err))
 	}
 
 	output := WriteDataOutput{
 		Database:  input.Database,
 		Table:     input.Table,
 		Operation: operation,
-		Inserted:  writeResp.Inserted,
-		Replaced:  writeResp.Replaced,
-		Unchanged: writeResp.Unchanged,
-		Deleted:   writeResp.Deleted,
-		Errors:    writeResp.Errors,
-	}
-	if writeResp.FirstError != "" {
-		output.FirstError = writeResp.FirstError
 	}
+	addWriteResult(&output, result, returnedChanges(input))
+	output.NotFound = notFound
 
+	// Documents RethinkDB rejected are reported in the output, alongside
+	// whatever the rest of the write did.
+	if output.Errors > 0 {
+		return &mcp.CallToolResult{IsError: true}, output, nil
+	}
 	return nil, output, nil
 }
 
@@ -396,6 +800,14 @@ func (s *RethinkDBServer) Aggregate(ctx context.Context, req *mcp.CallToolReques
 		return nil, AggregateOutput{}, fmt.Errorf("database and table names are required")
 	}
 
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, AggregateOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "aggregate", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("aggregate on %s.%s", input.Database, input.Table)
+
 	switch input.Operation {
 	case "count", "sum", "avg", "min", "max", "group":
 		// valid
@@ -407,10 +819,15 @@ func (s *RethinkDBServer) Aggregate(ctx context.Context, req *mcp.CallToolReques
 		return nil, AggregateOutput{}, fmt.Errorf("field is required for %s operation", input.Operation)
 	}
 
+	filter, err := compileFilter(input.Filter)
+	if err != nil {
+		return nil, AggregateOutput{}, fmt.Errorf("invalid filter: %w", err)
+	}
+
 	query := r.DB(input.Database).Table(input.Table)
 
-	if len(input.Filter) > 0 {
-		query = query.Filter(input.Filter)
+	if filter != nil {
+		query = query.Filter(filter)
 	}
 
 	var term r.Term
@@ -443,9 +860,14 @@ func (s *RethinkDBServer) Aggregate(ctx context.Context, req *mcp.CallToolReques
 		}
 	}
 
-	cursor, err := term.Run(s.session)
+	if err := s.checkReadOnly(term); err != nil {
+		return nil, AggregateOutput{}, err
+	}
+	what = describeQuery(what, term)
+
+	cursor, err := term.Run(s.session, r.RunOpts{Context: ctx, Profile: input.Profile})
 	if err != nil {
-		return nil, AggregateOutput{}, fmt.Errorf("failed to execute aggregation: %w", err)
+		return nil, AggregateOutput{}, queryError(ctx, what, fmt.Errorf("failed to execute aggregation: %w", err))
 	}
 	defer cursor.Close()
 
@@ -453,21 +875,24 @@ func (s *RethinkDBServer) Aggregate(ctx context.Context, req *mcp.CallToolReques
 	if input.Operation == "group" {
 		var results []interface{}
 		if err := cursor.All(&results); err != nil {
-			return nil, AggregateOutput{}, fmt.Errorf("failed to read group results: %w", err)
+			return nil, AggregateOutput{}, queryError(ctx, what, fmt.Errorf("failed to read group results: %w", err))
 		}
 		value = results
 	} else {
 		if err := cursor.One(&value); err != nil {
-			return nil, AggregateOutput{}, fmt.Errorf("failed to read aggregation result: %w", err)
+			return nil, AggregateOutput{}, queryError(ctx, what, fmt.Errorf("failed to read aggregation result: %w", err))
 		}
 	}
 
+	s.history.record("aggregate", input.Database, input.Table, input.Filter, "")
+
 	return nil, AggregateOutput{
 		Database:  input.Database,
 		Table:     input.Table,
 		Operation: input.Operation,
 		Field:     input.Field,
 		Value:     value,
+		Profile:   profileOf(cursor, input.Profile),
 	}, nil
 }
 
@@ -486,10 +911,41 @@ func (s *RethinkDBServer) AdvancedQuery(ctx context.Context, req *mcp.CallToolRe
 		return nil, AdvancedQueryOutput{}, fmt.Errorf("database and table names are required")
 	}
 
+	tables := []string{input.Table}
+	if input.Operation == "eq_join" && input.JoinTable != "" {
+		tables = append(tables, input.JoinTable)
+	}
+	if err := s.checkTables(input.Database, tables...); err != nil {
+		return nil, AdvancedQueryOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "advanced_query", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("advanced_query on %s.%s", input.Database, input.Table)
+
 	limit := applyLimit(input.Limit)
 
+	// The cursor and page size may change between pages of the same query.
+	shapeInput := input
+	shapeInput.Cursor = ""
+	shapeInput.Limit = 0
+	shapeInput.Profile = false
+	shape := queryShape("advanced_query", shapeInput)
+	after, err := decodeCursor(input.Cursor, shape)
+	if err != nil {
+		return nil, AdvancedQueryOutput{}, err
+	}
+
+	pk, err := s.primaryKey(ctx, input.Database, input.Table)
+	if err != nil {
+		return nil, AdvancedQueryOutput{}, queryError(ctx, what, err)
+	}
+
 	table := r.DB(input.Database).Table(input.Table)
-	var results []interface{}
+	// Every operation pages through an index: the primary key unless the
+	// operation ranges over a secondary index, keyed by indexField.
+	index, indexField := pk, pk
+	var query r.Term
 
 	switch input.Operation {
 	case "eq_join":
@@ -499,14 +955,8 @@ func (s *RethinkDBServer) AdvancedQuery(ctx context.Context, req *mcp.CallToolRe
 		if input.JoinTable == "" {
 			return nil, AdvancedQueryOutput{}, fmt.Errorf("join_table is required for eq_join")
 		}
-		cursor, err := table.EqJoin(input.JoinField, r.DB(input.Database).Table(input.JoinTable)).Limit(limit).Run(s.session)
-		if err != nil {
-			return nil, AdvancedQueryOutput{}, fmt.Errorf("failed to execute eq_join: %w", err)
-		}
-		defer cursor.Close()
-		if err := cursor.All(&results); err != nil {
-			return nil, AdvancedQueryOutput{}, fmt.Errorf("failed to read eq_join results: %w", err)
-		}
+		query = pageByIndex(table, pk, pk, pk, r.MinVal, r.MaxVal, after).
+			EqJoin(input.JoinField, r.DB(input.Database).Table(input.JoinTable), r.EqJoinOpts{Ordered: true})
 
 	case "between":
 		if input.Index == "" {
@@ -527,14 +977,16 @@ func (s *RethinkDBServer) AdvancedQuery(ctx context.Context, req *mcp.CallToolRe
 		} else {
 			upper = r.MaxVal
 		}
-		cursor, err := table.Between(lower, upper, r.BetweenOpts{Index: input.Index}).Limit(limit).Run(s.session)
-		if err != nil {
-			return nil, AdvancedQueryOutput{}, fmt.Errorf("failed to execute between: %w", err)
-		}
-		defer cursor.Close()
-		if err := cursor.All(&results); err != nil {
-			return nil, AdvancedQueryOutput{}, fmt.Errorf("failed to read between results: %w", err)
+		index = input.Index
+		if index != pk {
+			if indexField, err = s.indexField(ctx, input.Database, input.Table, index); err != nil {
+				return nil, AdvancedQueryOutput{}, queryError(ctx, what, err)
+			}
+			if after != nil && indexField == "" {
+				return nil, AdvancedQueryOutput{}, fmt.Errorf("invalid cursor: index %q cannot be paged through", index)
+			}
 		}
+		query = pageByIndex(table, index, indexField, pk, lower, upper, after)
 
 	case "contains":
 		if input.ContainsField == "" {
@@ -548,44 +1000,73 @@ func (s *RethinkDBServer) AdvancedQuery(ctx context.Context, req *mcp.CallToolRe
 		} else {
 			return nil, AdvancedQueryOutput{}, fmt.Errorf("contains_value is required for contains")
 		}
-		cursor, err := table.Filter(func(row r.Term) r.Term {
+		query = pageByIndex(table, pk, pk, pk, r.MinVal, r.MaxVal, after).Filter(func(row r.Term) r.Term {
 			return row.Field(input.ContainsField).Contains(val)
-		}).Limit(limit).Run(s.session)
-		if err != nil {
-			return nil, AdvancedQueryOutput{}, fmt.Errorf("failed to execute contains: %w", err)
-		}
-		defer cursor.Close()
-		if err := cursor.All(&results); err != nil {
-			return nil, AdvancedQueryOutput{}, fmt.Errorf("failed to read contains results: %w", err)
-		}
+		})
 
 	case "map":
 		if len(input.MapExpr) == 0 {
 			return nil, AdvancedQueryOutput{}, fmt.Errorf("map_expr is required for map")
 		}
-		var fields []interface{}
+		// The primary key is always plucked so the next page can resume.
+		fields := []interface{}{pk}
 		for k := range input.MapExpr {
-			fields = append(fields, k)
-		}
-		cursor, err := table.Pluck(fields...).Limit(limit).Run(s.session)
-		if err != nil {
-			return nil, AdvancedQueryOutput{}, fmt.Errorf("failed to execute map/pluck: %w", err)
-		}
-		defer cursor.Close()
-		if err := cursor.All(&results); err != nil {
-			return nil, AdvancedQueryOutput{}, fmt.Errorf("failed to read map results: %w", err)
+			if k != pk {
+				fields = append(fields, k)
+			}
 		}
+		query = pageByIndex(table, pk, pk, pk, r.MinVal, r.MaxVal, after).Pluck(fields...)
 
 	default:
 		return nil, AdvancedQueryOutput{}, fmt.Errorf("invalid operation %q: must be one of eq_join, between, contains, map", input.Operation)
 	}
 
+	// Fetch one extra document to learn whether another page exists.
+	query = query.Limit(limit + 1)
+
+	if err := s.checkReadOnly(query); err != nil {
+		return nil, AdvancedQueryOutput{}, err
+	}
+	what = describeQuery(what, query)
+
+	cursor, err := query.Run(s.session, r.RunOpts{Context: ctx, Profile: input.Profile})
+	if err != nil {
+		return nil, AdvancedQueryOutput{}, queryError(ctx, what, fmt.Errorf("failed to execute %s: %w", input.Operation, err))
+	}
+	defer cursor.Close()
+
+	var results []interface{}
+	if err := cursor.All(&results); err != nil {
+		return nil, AdvancedQueryOutput{}, queryError(ctx, what, fmt.Errorf("failed to read %s results: %w", input.Operation, err))
+	}
+
+	var next, note string
+	if len(results) > limit {
+		results = results[:limit]
+		last := results[limit-1]
+		if input.Operation == "eq_join" {
+			if joined, ok := last.(map[string]interface{}); ok {
+				last = joined["left"]
+			}
+		}
+		if index != pk && indexField == "" {
+			// Compound, multi and function indexes have no single value
+			// to resume from, so only the first page is returned.
+			note = fmt.Sprintf("only the first %d results are returned: index %q is not on a single top-level field, so it cannot be paged through; narrow the bounds or raise the limit", limit, index)
+		} else if next, err = nextCursor(last, shape, index, indexField, pk); err != nil {
+			return nil, AdvancedQueryOutput{}, err
+		}
+	}
+
 	return nil, AdvancedQueryOutput{
-		Database:  input.Database,
-		Table:     input.Table,
-		Operation: input.Operation,
-		Count:     len(results),
-		Results:   results,
+		Database:   input.Database,
+		Table:      input.Table,
+		Operation:  input.Operation,
+		Count:      len(results),
+		Results:    results,
+		NextCursor: next,
+		Note:       note,
+		Profile:    profileOf(cursor, input.Profile),
 	}, nil
 }
 
@@ -594,10 +1075,33 @@ func (s *RethinkDBServer) SchemaInspector(ctx context.Context, req *mcp.CallTool
 		return nil, SchemaInspectorOutput{}, fmt.Errorf("database and table names are required")
 	}
 
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, SchemaInspectorOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "schema_inspector", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("schema_inspector on %s.%s", input.Database, input.Table)
+
+	output, err := s.inspectSchema(ctx, what, input)
+	if err != nil {
+		return nil, SchemaInspectorOutput{}, err
+	}
+	return nil, output, nil
+}
+
+// inspectSchema samples a table and infers its schema. It is shared by
+// schema_inspector and generate_types; callers check access and set the
+// timeout.
+func (s *RethinkDBServer) inspectSchema(ctx context.Context, what string, input SchemaInspectorInput) (SchemaInspectorOutput, error) {
 	sampleSize := input.SampleSize
 	if sampleSize <= 0 {
 		sampleSize = 100
 	}
+	strategy, err := parseSampleStrategy(input.Strategy)
+	if err != nil {
+		return SchemaInspectorOutput{}, err
+	}
 
 	output := SchemaInspectorOutput{
 		Database:   input.Database,
@@ -606,21 +1110,21 @@ func (s *RethinkDBServer) SchemaInspector(ctx context.Context, req *mcp.CallTool
 	}
 
 	// Get primary key
-	infoCursor, err := r.DB(input.Database).Table(input.Table).Info().Run(s.session)
+	infoCursor, err := r.DB(input.Database).Table(input.Table).Info().Run(s.session, r.RunOpts{Context: ctx})
 	if err != nil {
-		return nil, SchemaInspectorOutput{}, fmt.Errorf("failed to get table info: %w", err)
+		return SchemaInspectorOutput{}, queryError(ctx, what, fmt.Errorf("failed to get table info: %w", err))
 	}
 	defer infoCursor.Close()
 	var info map[string]interface{}
 	if err := infoCursor.One(&info); err != nil {
-		return nil, SchemaInspectorOutput{}, fmt.Errorf("failed to read table info: %w", err)
+		return SchemaInspectorOutput{}, queryError(ctx, what, fmt.Errorf("failed to read table info: %w", err))
 	}
 	if pk, ok := info["primary_key"].(string); ok {
 		output.PrimaryKey = pk
 	}
 
 	// Get indexes
-	indexCursor, err := r.DB(input.Database).Table(input.Table).IndexList().Run(s.session)
+	indexCursor, err := r.DB(input.Database).Table(input.Table).IndexList().Run(s.session, r.RunOpts{Context: ctx})
 	if err == nil {
 		defer indexCursor.Close()
 		var indexes []string
@@ -633,7 +1137,7 @@ func (s *RethinkDBServer) SchemaInspector(ctx context.Context, req *mcp.CallTool
 	}
 
 	// Get doc count
-	countCursor, err := r.DB(input.Database).Table(input.Table).Count().Run(s.session)
+	countCursor, err := r.DB(input.Database).Table(input.Table).Count().Run(s.session, r.RunOpts{Context: ctx})
 	if err == nil {
 		defer countCursor.Close()
 		var count int
@@ -643,55 +1147,25 @@ func (s *RethinkDBServer) SchemaInspector(ctx context.Context, req *mcp.CallTool
 	}
 
 	// Sample documents to infer schema
-	cursor, err := r.DB(input.Database).Table(input.Table).Limit(sampleSize).Run(s.session)
+	sample, err := s.sampleDocuments(ctx, input.Database, input.Table, sampleRequest{
+		strategy:  strategy,
+		size:      sampleSize,
+		timeIndex: input.TimeIndex,
+		pk:        output.PrimaryKey,
+		indexes:   output.Indexes,
+		docCount:  output.DocCount,
+	})
 	if err != nil {
-		return nil, SchemaInspectorOutput{}, fmt.Errorf("failed to sample documents: %w", err)
+		return SchemaInspectorOutput{}, queryError(ctx, what, err)
 	}
-	defer cursor.Close()
 
-	var docs []map[string]interface{}
-	if err := cursor.All(&docs); err != nil {
-		return nil, SchemaInspectorOutput{}, fmt.Errorf("failed to read sample documents: %w", err)
-	}
+	output.Strategy = sample.strategy
+	output.Sampled = len(sample.docs)
+	output.Confidence = sampleConfidence(output.Sampled, output.DocCount)
+	output.Note = sample.note
+	output.Fields = inferSchema(sample.docs)
 
-	// Collect all field names and their types
-	fieldTypes := make(map[string]string)
-	for _, doc := range docs {
-		for k, v := range doc {
-			if _, exists := fieldTypes[k]; !exists {
-				fieldTypes[k] = inferType(v)
-			}
-		}
-	}
-
-	fields := make([]FieldInfo, 0, len(fieldTypes))
-	for name, typ := range fieldTypes {
-		fields = append(fields, FieldInfo{Name: name, Type: typ})
-	}
-	output.Fields = fields
-
-	return nil, output, nil
-}
-
-func inferType(v interface{}) string {
-	if v == nil {
-		return "null"
-	}
-	rt := reflect.TypeOf(v)
-	switch rt.Kind() {
-	case reflect.String:
-		return "string"
-	case reflect.Float64, reflect.Float32, reflect.Int, reflect.Int64:
-		return "number"
-	case reflect.Bool:
-		return "bool"
-	case reflect.Slice:
-		return "array"
-	case reflect.Map:
-		return "object"
-	default:
-		return rt.String()
-	}
+	return output, nil
 }
 
 func (s *RethinkDBServer) IndexInfo(ctx context.Context, req *mcp.CallToolRequest, input IndexInfoInput) (*mcp.CallToolResult, IndexInfoOutput, error) {
@@ -699,41 +1173,33 @@ func (s *RethinkDBServer) IndexInfo(ctx context.Context, req *mcp.CallToolReques
 		return nil, IndexInfoOutput{}, fmt.Errorf("database and table names are required")
 	}
 
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, IndexInfoOutput{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "index_info", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("index_info on %s.%s", input.Database, input.Table)
+
 	output := IndexInfoOutput{
 		Database: input.Database,
 		Table:    input.Table,
 	}
 
-	cursor, err := r.DB(input.Database).Table(input.Table).IndexStatus().Run(s.session)
+	cursor, err := r.DB(input.Database).Table(input.Table).IndexStatus().Run(s.session, r.RunOpts{Context: ctx})
 	if err != nil {
-		return nil, IndexInfoOutput{}, fmt.Errorf("failed to get index status: %w", err)
+		return nil, IndexInfoOutput{}, queryError(ctx, what, fmt.Errorf("failed to get index status: %w", err))
 	}
 	defer cursor.Close()
 
 	var statuses []map[string]interface{}
 	if err := cursor.All(&statuses); err != nil {
-		return nil, IndexInfoOutput{}, fmt.Errorf("failed to read index status: %w", err)
+		return nil, IndexInfoOutput{}, queryError(ctx, what, fmt.Errorf("failed to read index status: %w", err))
 	}
 
 	indexes := make([]IndexDetail, 0, len(statuses))
-	for _, s := range statuses {
-		detail := IndexDetail{}
-		if name, ok := s["index"].(string); ok {
-			detail.Name = name
-		}
-		if ready, ok := s["ready"].(bool); ok {
-			detail.Ready = ready
-		}
-		if multi, ok := s["multi"].(bool); ok {
-			detail.Multi = multi
-		}
-		if geo, ok := s["geo"].(bool); ok {
-			detail.Geo = geo
-		}
-		if outdated, ok := s["outdated"].(bool); ok {
-			detail.Outdated = outdated
-		}
-		indexes = append(indexes, detail)
+	for _, status := range statuses {
+		indexes = append(indexes, indexDetail(status))
 	}
 	output.Indexes = indexes
 
@@ -743,6 +1209,9 @@ func (s *RethinkDBServer) IndexInfo(ctx context.Context, req *mcp.CallToolReques
 // ─── Tool Registration ──────────────────────────────────────────────────────
 
 func (s *RethinkDBServer) RegisterTools(mcpServer *mcp.Server) {
+	s.mcpServer = mcpServer
+	mcpServer.AddReceivingMiddleware(s.hideOtherWatches)
+
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "list_databases",
 		Description: "List all databases in RethinkDB",
@@ -763,10 +1232,46 @@ func (s *RethinkDBServer) RegisterTools(mcpServer *mcp.Server) {
 		Description: "Get table information including primary key, indexes, and document count",
 	}, s.TableInfo)
 
-	mcp.AddTool(mcpServer, &mcp.Tool{
-		Name:        "write_data",
-		Description: "Write data to a RethinkDB table. Supports insert, update, upsert, and delete operations. Data can be a single document or an array of documents.",
-	}, s.WriteData)
+	if !s.readOnly {
+		mcp.AddTool(mcpServer, &mcp.Tool{
+			Name:        "write_data",
+			Description: "Write data to a RethinkDB table. Supports insert, update, upsert, delete and compare_and_set operations. Data can be a single document or an array of documents; updates may instead apply update operators ($set, $inc, $unset, $push, $pull, $currentTime) to documents selected by keys or filter.",
+		}, s.WriteData)
+	}
+
+	if s.audit != nil {
+		mcp.AddTool(mcpServer, &mcp.Tool{
+			Name:        "list_audit_log",
+			Description: "List recorded write_data calls, newest first, with the calling client, the arguments, the affected primary keys and each document's old and new values. Filter by database, table, operation or time.",
+		}, s.ListAuditLog)
+	}
+
+	if s.admin && !s.readOnly {
+		mcp.AddTool(mcpServer, &mcp.Tool{
+			Name:        "db_create",
+			Description: "Create a RethinkDB database.",
+		}, s.DBCreate)
+
+		mcp.AddTool(mcpServer, &mcp.Tool{
+			Name:        "db_drop",
+			Description: "Drop a RethinkDB database and all its tables. The first call returns the tables that would be dropped and a confirm token; call again with confirm set to the token to drop.",
+		}, s.DBDrop)
+
+		mcp.AddTool(mcpServer, &mcp.Tool{
+			Name:        "table_create",
+			Description: "Create a RethinkDB table, optionally with a primary key, number of shards and replicas, and durability.",
+		}, s.TableCreate)
+
+		mcp.AddTool(mcpServer, &mcp.Tool{
+			Name:        "table_drop",
+			Description: "Drop a RethinkDB table. The first call returns the document count and a confirm token; call again with confirm set to the token to drop.",
+		}, s.TableDrop)
+
+		mcp.AddTool(mcpServer, &mcp.Tool{
+			Name:        "table_rename",
+			Description: "Rename a RethinkDB table by updating rethinkdb.table_config.",
+		}, s.TableRename)
+	}
 
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "aggregate",
@@ -778,13 +1283,65 @@ func (s *RethinkDBServer) RegisterTools(mcpServer *mcp.Server) {
 		Description: "Run advanced queries on a RethinkDB table: eq_join (join two tables by field), between (range query on an index), contains (filter by array field contents), or map (pluck specific fields from documents).",
 	}, s.AdvancedQuery)
 
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "geo_query",
+		Description: "Run geospatial queries on a RethinkDB table using GeoJSON points, lines, polygons or circles: get_intersecting (documents intersecting a geometry, via a geo index), get_nearest (documents nearest a point, with max_dist, unit and max_results), or includes (documents whose geometry lies within a polygon or circle). Geometries in results are returned as GeoJSON.",
+	}, s.GeoQuery)
+
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "schema_inspector",
 		Description: "Inspect the schema of a RethinkDB table by sampling documents. Returns field names and inferred types, primary key, indexes, and document count.",
 	}, s.SchemaInspector)
 
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "generate_types",
+		Description: "Generate a JSON Schema (draft 2020-12), Go structs with json and rethinkdb tags, and TypeScript interfaces from a table's inferred schema. Accepts the same sampling options as schema_inspector.",
+	}, s.GenerateTypes)
+
 	mcp.AddTool(mcpServer, &mcp.Tool{
 		Name:        "index_info",
 		Description: "Get detailed information about all secondary indexes on a RethinkDB table, including ready status, multi, geo, and outdated flags.",
 	}, s.IndexInfo)
+
+	if !s.readOnly {
+		mcp.AddTool(mcpServer, &mcp.Tool{
+			Name:        "index_create",
+			Description: "Create a secondary index on a RethinkDB table: on a field, on several fields (compound), or on a ReQL function expression, optionally multi or geo. Set wait to block until it is ready.",
+		}, s.IndexCreate)
+
+		mcp.AddTool(mcpServer, &mcp.Tool{
+			Name:        "index_drop",
+			Description: "Drop a secondary index from a RethinkDB table.",
+		}, s.IndexDrop)
+
+		mcp.AddTool(mcpServer, &mcp.Tool{
+			Name:        "index_rename",
+			Description: "Rename a secondary index, optionally overwriting an existing index with the new name.",
+		}, s.IndexRename)
+	}
+
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "index_wait",
+		Description: "Wait until secondary indexes on a RethinkDB table are ready, reporting build progress with progress notifications.",
+	}, s.IndexWait)
+
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "suggest_indexes",
+		Description: "Recommend simple and compound secondary indexes for a RethinkDB table based on the filter and order_by fields of earlier query_table and aggregate calls, with the queries that would benefit and the table's document count.",
+	}, s.SuggestIndexes)
+
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "run_reql",
+		Description: "Run a ReQL query written in the JavaScript syntax of the Data Explorer, e.g. r.db('app').table('users').filter(r.row('age').gt(30)).pluck('name'). Only whitelisted read methods are allowed; r.js, r.http and writes are rejected unless the server enables them. Tables must be referenced as r.db('<name>').table('<name>').",
+	}, s.RunReQL)
+
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "watch_table",
+		Description: "Open a changefeed on a RethinkDB table, optionally filtered and including the initial documents. The latest change events (old_val/new_val) are buffered in a resource at the returned uri; subscribe to it for resources/updated notifications. The feed closes with unwatch_table, when the session ends or when the feed itself ends; the server may limit how many feeds a session keeps open.",
+	}, s.WatchTable)
+
+	mcp.AddTool(mcpServer, &mcp.Tool{
+		Name:        "unwatch_table",
+		Description: "Close a changefeed opened with watch_table and remove its resource.",
+	}, s.UnwatchTable)
 }
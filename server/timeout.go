@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ErrQueryTimeout is returned when a query does not finish within its
+// timeout. The error message names the tool and the query that was running.
+var ErrQueryTimeout = errors.New("query timed out")
+
+// maxQueryDescription bounds how much of a query's string form is included
+// in timeout errors, since write queries may embed whole documents.
+const maxQueryDescription = 300
+
+// Timeouts configures how long tool queries may run before they are stopped
+// on the server. A zero duration means no timeout.
+type Timeouts struct {
+	// Default applies to every tool without an entry in PerTool.
+	Default time.Duration
+	// PerTool overrides Default for the named tools, e.g. "aggregate".
+	PerTool map[string]time.Duration
+}
+
+// For returns the timeout configured for tool.
+func (t Timeouts) For(tool string) time.Duration {
+	if d, ok := t.PerTool[tool]; ok {
+		return d
+	}
+	return t.Default
+}
+
+type timeoutKey struct{}
+
+// withTimeout derives the context queries for a tool call run under. A
+// positive timeoutMs from the call input replaces the configured timeout
+// when it is shorter, so callers cannot outlast the operator's limit. The
+// returned cancel function must be called once the results are read,
+// because cursors keep fetching batches with the context.
+func (s *RethinkDBServer) withTimeout(ctx context.Context, tool string, timeoutMs int) (context.Context, context.CancelFunc) {
+	timeout := s.timeouts.For(tool)
+	if requested := time.Duration(timeoutMs) * time.Millisecond; requested > 0 && (timeout <= 0 || requested < timeout) {
+		timeout = requested
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	ctx = context.WithValue(ctx, timeoutKey{}, timeout)
+	return context.WithTimeout(ctx, timeout)
+}
+
+// describeQuery names a running query for error messages.
+func describeQuery(tool string, term r.Term) string {
+	query := term.String()
+	if len(query) > maxQueryDescription {
+		query = query[:maxQueryDescription] + "..."
+	}
+	return fmt.Sprintf("%s: %s", tool, query)
+}
+
+// queryError replaces err with a timeout or cancellation error naming what
+// was running when ctx ended. Other errors are returned unchanged.
+func queryError(ctx context.Context, what string, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		timeout, _ := ctx.Value(timeoutKey{}).(time.Duration)
+		return fmt.Errorf("%w after %s while running %s", ErrQueryTimeout, timeout, what)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("query cancelled while running %s: %w", what, context.Canceled)
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ─── query timeouts ─────────────────────────────────────────────────────────
+
+func TestTimeouts_For(t *testing.T) {
+	timeouts := Timeouts{
+		Default: 30 * time.Second,
+		PerTool: map[string]time.Duration{"aggregate": 2 * time.Minute},
+	}
+	if got := timeouts.For("aggregate"); got != 2*time.Minute {
+		t.Errorf("expected per-tool timeout 2m, got %s", got)
+	}
+	if got := timeouts.For("query_table"); got != 30*time.Second {
+		t.Errorf("expected default timeout 30s, got %s", got)
+	}
+	if got := (Timeouts{}).For("query_table"); got != 0 {
+		t.Errorf("expected no timeout, got %s", got)
+	}
+}
+
+func TestWithTimeout_CapsTimeoutMs(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithTimeouts(Timeouts{Default: time.Second}))
+	for _, tc := range []struct {
+		timeoutMs int
+		want      time.Duration
+	}{
+		{0, time.Second},
+		{100, 100 * time.Millisecond},
+		{60000, time.Second},
+	} {
+		ctx, cancel := srv.withTimeout(context.Background(), "query_table", tc.timeoutMs)
+		if got, _ := ctx.Value(timeoutKey{}).(time.Duration); got != tc.want {
+			t.Errorf("timeout_ms %d: expected %s, got %s", tc.timeoutMs, tc.want, got)
+		}
+		cancel()
+	}
+
+	ctx, cancel := newTestServer().withTimeout(context.Background(), "query_table", 60000)
+	defer cancel()
+	if got, _ := ctx.Value(timeoutKey{}).(time.Duration); got != time.Minute {
+		t.Errorf("expected timeout_ms to apply without a server timeout, got %s", got)
+	}
+}
+
+func TestQueryError_PassesThroughOtherErrors(t *testing.T) {
+	want := errors.New("boom")
+	if got := queryError(context.Background(), "query_table", want); got != want {
+		t.Errorf("expected error to be returned unchanged, got %v", got)
+	}
+}
+
+func TestRunReQL_TimeoutMs(t *testing.T) {
+	srv := newTestServer()
+	_, _, err := srv.RunReQL(context.Background(), &mcp.CallToolRequest{}, RunReQLInput{
+		Query:     "r.range(100000000).count()",
+		TimeoutMs: 10,
+	})
+	if !errors.Is(err, ErrQueryTimeout) {
+		t.Fatalf("expected ErrQueryTimeout, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "run_reql") || !strings.Contains(err.Error(), "10ms") {
+		t.Errorf("expected error to name the tool and timeout, got %v", err)
+	}
+}
+
+func TestRunReQL_ServerDefaultTimeout(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithTimeouts(Timeouts{
+		PerTool: map[string]time.Duration{"run_reql": 10 * time.Millisecond},
+	}))
+	_, _, err := srv.RunReQL(context.Background(), &mcp.CallToolRequest{}, RunReQLInput{
+		Query: "r.range(100000000).count()",
+	})
+	if !errors.Is(err, ErrQueryTimeout) {
+		t.Fatalf("expected ErrQueryTimeout, got %v", err)
+	}
+}
+
+func TestQueryTable_CancelledContext(t *testing.T) {
+	srv := newTestServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := srv.QueryTable(ctx, &mcp.CallToolRequest{}, QueryTableInput{
+		Database: testDB,
+		Table:    testTable,
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestQueryTable_TimeoutMsNotReached(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.QueryTable(context.Background(), &mcp.CallToolRequest{}, QueryTableInput{
+		Database:  testDB,
+		Table:     testTable,
+		TimeoutMs: 5000,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Count != 3 {
+		t.Errorf("expected 3 documents, got %d", output.Count)
+	}
+}
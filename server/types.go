@@ -0,0 +1,567 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Output formats of generate_types.
+const (
+	formatJSONSchema = "json_schema"
+	formatGo         = "go"
+	formatTypeScript = "typescript"
+)
+
+// jsonSchemaDialect is the JSON Schema draft generated schemas declare.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// ─── Input/Output structs ────────────────────────────────────────────────────
+
+type GenerateTypesInput struct {
+	Database   string   `json:"database" jsonschema:"The database name"`
+	Table      string   `json:"table" jsonschema:"The table name"`
+	TypeName   string   `json:"type_name,omitempty" jsonschema:"Name of the generated top-level type (default: the table name in PascalCase)"`
+	Formats    []string `json:"formats,omitempty" jsonschema:"Formats to generate: json_schema, go, typescript (default: all)"`
+	SampleSize int      `json:"sample_size,omitempty" jsonschema:"Number of documents to sample for schema inference (default 100)"`
+	Strategy   string   `json:"strategy,omitempty" jsonschema:"Sampling strategy: first (default), random, recent or stratified, as in schema_inspector"`
+	TimeIndex  string   `json:"time_index,omitempty" jsonschema:"Secondary index ordering documents by time, for the recent strategy"`
+	TimeoutMs  int      `json:"timeout_ms,omitempty" jsonschema:"Optional query timeout in milliseconds; it can shorten but not extend the server timeout"`
+}
+
+type GenerateTypesOutput struct {
+	Database   string                 `json:"database"`
+	Table      string                 `json:"table"`
+	TypeName   string                 `json:"type_name"`
+	Strategy   string                 `json:"strategy"`
+	Sampled    int                    `json:"sampled"`
+	Confidence float64                `json:"confidence"`
+	JSONSchema map[string]interface{} `json:"json_schema,omitempty"`
+	Go         string                 `json:"go,omitempty"`
+	TypeScript string                 `json:"typescript,omitempty"`
+}
+
+// ─── Handler ─────────────────────────────────────────────────────────────────
+
+// GenerateTypes infers a table's schema like schema_inspector and renders it
+// as a JSON Schema, Go structs and TypeScript interfaces. Fields missing from
+// some documents are optional, fields seen with several types become unions,
+// and nested objects become nested types.
+func (s *RethinkDBServer) GenerateTypes(ctx context.Context, req *mcp.CallToolRequest, input GenerateTypesInput) (*mcp.CallToolResult, GenerateTypesOutput, error) {
+	if input.Database == "" || input.Table == "" {
+		return nil, GenerateTypesOutput{}, fmt.Errorf("database and table names are required")
+	}
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, GenerateTypesOutput{}, err
+	}
+
+	formats := input.Formats
+	if len(formats) == 0 {
+		formats = []string{formatJSONSchema, formatGo, formatTypeScript}
+	}
+	for _, f := range formats {
+		if f != formatJSONSchema && f != formatGo && f != formatTypeScript {
+			return nil, GenerateTypesOutput{}, fmt.Errorf("unknown format %q: use json_schema, go or typescript", f)
+		}
+	}
+
+	typeName := input.TypeName
+	if typeName == "" {
+		typeName = exportedName(input.Table)
+	} else if !isIdentifier(typeName) {
+		return nil, GenerateTypesOutput{}, fmt.Errorf("type_name %q is not a valid identifier", typeName)
+	}
+
+	ctx, cancel := s.withTimeout(ctx, "generate_types", input.TimeoutMs)
+	defer cancel()
+	what := fmt.Sprintf("generate_types on %s.%s", input.Database, input.Table)
+
+	schema, err := s.inspectSchema(ctx, what, SchemaInspectorInput{
+		Database:   input.Database,
+		Table:      input.Table,
+		SampleSize: input.SampleSize,
+		Strategy:   input.Strategy,
+		TimeIndex:  input.TimeIndex,
+	})
+	if err != nil {
+		return nil, GenerateTypesOutput{}, err
+	}
+
+	root := buildTypeTree(schema.Fields)
+	output := GenerateTypesOutput{
+		Database:   input.Database,
+		Table:      input.Table,
+		TypeName:   typeName,
+		Strategy:   schema.Strategy,
+		Sampled:    schema.Sampled,
+		Confidence: schema.Confidence,
+	}
+	for _, f := range formats {
+		switch f {
+		case formatJSONSchema:
+			output.JSONSchema = generateJSONSchema(typeName, root)
+		case formatGo:
+			output.Go = generateGo(typeName, root, schema.PrimaryKey)
+		case formatTypeScript:
+			output.TypeScript = generateTypeScript(typeName, root)
+		}
+	}
+	return nil, output, nil
+}
+
+// ─── Type tree ───────────────────────────────────────────────────────────────
+
+// typeNode is a field in the tree rebuilt from schema_inspector's dotted
+// paths. The root and array element nodes have no info of their own.
+type typeNode struct {
+	name     string
+	info     *FieldInfo
+	children []*typeNode
+	// element holds the fields of objects found inside an array field.
+	element *typeNode
+}
+
+func (n *typeNode) child(name string) *typeNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &typeNode{name: name}
+	n.children = append(n.children, c)
+	return c
+}
+
+// buildTypeTree nests the fields reported by inferSchema. A path segment
+// ending in "[]" descends into the objects of an array field.
+func buildTypeTree(fields []FieldInfo) *typeNode {
+	root := &typeNode{}
+	for i := range fields {
+		segments := strings.Split(fields[i].Name, ".")
+		node := root
+		for j, segment := range segments {
+			name := strings.TrimSuffix(segment, "[]")
+			child := node.child(name)
+			if j == len(segments)-1 {
+				child.info = &fields[i]
+				break
+			}
+			if name != segment {
+				if child.element == nil {
+					child.element = &typeNode{}
+				}
+				node = child.element
+			} else {
+				node = child
+			}
+		}
+	}
+	sortTypeTree(root)
+	return root
+}
+
+func sortTypeTree(n *typeNode) {
+	if n == nil {
+		return
+	}
+	sort.Slice(n.children, func(i, j int) bool { return n.children[i].name < n.children[j].name })
+	for _, c := range n.children {
+		sortTypeTree(c)
+		sortTypeTree(c.element)
+	}
+}
+
+// fieldTypes returns a node's observed types and its array element types.
+func (n *typeNode) fieldTypes() (types, elements map[string]int) {
+	if n.info == nil {
+		return map[string]int{"object": 1}, nil
+	}
+	return n.info.Types, n.info.ElementTypes
+}
+
+// required reports whether a field is present in every parent object.
+func (n *typeNode) required() bool {
+	return n.info != nil && n.info.Required
+}
+
+// nonNullTypes returns the observed types other than null, most frequent
+// first, and whether null was observed.
+func nonNullTypes(types map[string]int) ([]string, bool) {
+	var out []string
+	for _, t := range typesByFrequency(types) {
+		if t != "null" {
+			out = append(out, t)
+		}
+	}
+	return out, types["null"] > 0
+}
+
+// ─── JSON Schema ─────────────────────────────────────────────────────────────
+
+func generateJSONSchema(typeName string, root *typeNode) map[string]interface{} {
+	schema := objectJSONSchema(root)
+	schema["$schema"] = jsonSchemaDialect
+	schema["title"] = typeName
+	return schema
+}
+
+func objectJSONSchema(n *typeNode) map[string]interface{} {
+	properties := make(map[string]interface{}, len(n.children))
+	required := []string{}
+	for _, c := range n.children {
+		types, elements := c.fieldTypes()
+		properties[c.name] = typesJSONSchema(types, c, elements, c.element)
+		if c.required() {
+			required = append(required, c.name)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// typesJSONSchema returns the schema of a value seen with the given types.
+// obj holds its fields when it is an object, and elem the fields of objects
+// in it when it is an array.
+func typesJSONSchema(types map[string]int, obj *typeNode, elements map[string]int, elem *typeNode) map[string]interface{} {
+	var alternatives []map[string]interface{}
+	for _, t := range typesByFrequency(types) {
+		switch t {
+		case "string":
+			alternatives = append(alternatives, map[string]interface{}{"type": "string"})
+		case "number":
+			alternatives = append(alternatives, map[string]interface{}{"type": "number"})
+		case "bool":
+			alternatives = append(alternatives, map[string]interface{}{"type": "boolean"})
+		case "null":
+			alternatives = append(alternatives, map[string]interface{}{"type": "null"})
+		case "time":
+			alternatives = append(alternatives, map[string]interface{}{"type": "string", "format": "date-time"})
+		case "binary":
+			alternatives = append(alternatives, map[string]interface{}{"type": "string", "contentEncoding": "base64"})
+		case "geometry":
+			alternatives = append(alternatives, map[string]interface{}{
+				"type":     "object",
+				"required": []string{"type", "coordinates"},
+				"properties": map[string]interface{}{
+					"type":        map[string]interface{}{"type": "string"},
+					"coordinates": map[string]interface{}{"type": "array"},
+				},
+			})
+		case "object":
+			if obj == nil {
+				obj = &typeNode{}
+			}
+			alternatives = append(alternatives, objectJSONSchema(obj))
+		case "array":
+			array := map[string]interface{}{"type": "array"}
+			if len(elements) > 0 {
+				array["items"] = typesJSONSchema(elements, elem, nil, nil)
+			}
+			alternatives = append(alternatives, array)
+		default:
+			alternatives = append(alternatives, map[string]interface{}{})
+		}
+	}
+
+	switch len(alternatives) {
+	case 0:
+		return map[string]interface{}{}
+	case 1:
+		return alternatives[0]
+	}
+	// Plain types combine into a type list; anything richer needs anyOf.
+	names := make([]interface{}, 0, len(alternatives))
+	for _, alt := range alternatives {
+		name, ok := alt["type"].(string)
+		if !ok || len(alt) != 1 {
+			anyOf := make([]interface{}, len(alternatives))
+			for i, a := range alternatives {
+				anyOf[i] = a
+			}
+			return map[string]interface{}{"anyOf": anyOf}
+		}
+		names = append(names, name)
+	}
+	return map[string]interface{}{"type": names}
+}
+
+// ─── Go ──────────────────────────────────────────────────────────────────────
+
+type goGenerator struct {
+	pk        string
+	decls     []string
+	typeNames map[string]bool
+	imports   map[string]bool
+}
+
+// generateGo renders root as Go struct definitions with json and rethinkdb
+// tags. The primary key tag has omitempty so inserts let RethinkDB generate
+// the key.
+func generateGo(typeName string, root *typeNode, pk string) string {
+	g := &goGenerator{pk: pk, typeNames: map[string]bool{}, imports: map[string]bool{}}
+	g.structType(typeName, root, true)
+
+	var b strings.Builder
+	if len(g.imports) > 0 {
+		paths := make([]string, 0, len(g.imports))
+		for path := range g.imports {
+			paths = append(paths, strconv.Quote(path))
+		}
+		sort.Strings(paths)
+		b.WriteString("import (\n\t" + strings.Join(paths, "\n\t") + "\n)\n\n")
+	}
+	b.WriteString(strings.Join(g.decls, "\n"))
+
+	src := b.String()
+	if formatted, err := format.Source([]byte(src)); err == nil {
+		src = string(formatted)
+	}
+	return src
+}
+
+// structType declares a struct for the fields of n and returns its name.
+func (g *goGenerator) structType(name string, n *typeNode, top bool) string {
+	name = uniqueName(name, g.typeNames)
+	slot := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	fieldNames := map[string]bool{}
+	var b strings.Builder
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	for _, c := range n.children {
+		types, elements := c.fieldTypes()
+		goType, union := g.fieldType(name+exportedName(c.name), types, c, elements, c.element)
+
+		jsonTag, rdbTag := c.name, c.name
+		if !c.required() {
+			jsonTag += ",omitempty"
+			rdbTag += ",omitempty"
+		} else if top && c.name == g.pk {
+			rdbTag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "\t%s %s `json:%q rethinkdb:%q`", uniqueName(exportedName(c.name), fieldNames), goType, jsonTag, rdbTag)
+		if union != "" {
+			fmt.Fprintf(&b, " // %s", union)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+
+	g.decls[slot] = b.String()
+	return name
+}
+
+// fieldType returns the Go type for a value seen with the given types, and
+// a description of the union when the value had several types and falls
+// back to interface{}. Nullable values become pointers unless the type can
+// already hold nil.
+func (g *goGenerator) fieldType(name string, types map[string]int, obj *typeNode, elements map[string]int, elem *typeNode) (string, string) {
+	nonNull, nullable := nonNullTypes(types)
+	if len(nonNull) != 1 {
+		if len(nonNull) > 1 {
+			return "interface{}", strings.Join(typesByFrequency(types), " | ")
+		}
+		return "interface{}", ""
+	}
+
+	var goType string
+	pointer := nullable
+	switch nonNull[0] {
+	case "string":
+		goType = "string"
+	case "number":
+		goType = "float64"
+	case "bool":
+		goType = "bool"
+	case "time":
+		goType = "time.Time"
+		g.imports["time"] = true
+	case "binary":
+		goType, pointer = "[]byte", false
+	case "geometry":
+		goType = "types.Geometry"
+		g.imports["gopkg.in/rethinkdb/rethinkdb-go.v6/types"] = true
+	case "object":
+		if obj == nil {
+			obj = &typeNode{}
+		}
+		goType = g.structType(name, obj, false)
+	case "array":
+		elemType := "interface{}"
+		if len(elements) > 0 {
+			elemType, _ = g.fieldType(name+"Item", elements, elem, nil, nil)
+		}
+		goType, pointer = "[]"+elemType, false
+	default:
+		goType, pointer = "interface{}", false
+	}
+	if pointer {
+		goType = "*" + goType
+	}
+	return goType, ""
+}
+
+// ─── TypeScript ──────────────────────────────────────────────────────────────
+
+type tsGenerator struct {
+	decls     []string
+	typeNames map[string]bool
+}
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// generateTypeScript renders root as TypeScript interfaces. Times are Date
+// and binary values Uint8Array, as the JavaScript driver returns them.
+func generateTypeScript(typeName string, root *typeNode) string {
+	g := &tsGenerator{typeNames: map[string]bool{}}
+	g.interfaceType(typeName, root)
+	return strings.Join(g.decls, "\n")
+}
+
+func (g *tsGenerator) interfaceType(name string, n *typeNode) string {
+	name = uniqueName(name, g.typeNames)
+	slot := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "export interface %s {\n", name)
+	for _, c := range n.children {
+		types, elements := c.fieldTypes()
+		property := c.name
+		if !tsIdentifier.MatchString(property) {
+			property = strconv.Quote(property)
+		}
+		if !c.required() {
+			property += "?"
+		}
+		fmt.Fprintf(&b, "  %s: %s;\n", property, g.fieldType(name+exportedName(c.name), types, c, elements, c.element))
+	}
+	b.WriteString("}\n")
+
+	g.decls[slot] = b.String()
+	return name
+}
+
+func (g *tsGenerator) fieldType(name string, types map[string]int, obj *typeNode, elements map[string]int, elem *typeNode) string {
+	var alternatives []string
+	for _, t := range typesByFrequency(types) {
+		switch t {
+		case "string":
+			alternatives = append(alternatives, "string")
+		case "number":
+			alternatives = append(alternatives, "number")
+		case "bool":
+			alternatives = append(alternatives, "boolean")
+		case "null":
+			alternatives = append(alternatives, "null")
+		case "time":
+			alternatives = append(alternatives, "Date")
+		case "binary":
+			alternatives = append(alternatives, "Uint8Array")
+		case "geometry":
+			alternatives = append(alternatives, "{ type: string; coordinates: unknown[] }")
+		case "object":
+			if obj == nil {
+				obj = &typeNode{}
+			}
+			alternatives = append(alternatives, g.interfaceType(name, obj))
+		case "array":
+			elemType := "unknown"
+			if len(elements) > 0 {
+				elemType = g.fieldType(name+"Item", elements, elem, nil, nil)
+			}
+			if strings.Contains(elemType, " ") {
+				elemType = "(" + elemType + ")"
+			}
+			alternatives = append(alternatives, elemType+"[]")
+		default:
+			alternatives = append(alternatives, "unknown")
+		}
+	}
+	if len(alternatives) == 0 {
+		return "unknown"
+	}
+	return strings.Join(alternatives, " | ")
+}
+
+// ─── Naming ──────────────────────────────────────────────────────────────────
+
+// commonInitialisms are written in upper case in Go and TypeScript names.
+var commonInitialisms = map[string]bool{
+	"API": true, "HTML": true, "HTTP": true, "ID": true, "IP": true, "JSON": true,
+	"SKU": true, "SQL": true, "URI": true, "URL": true, "UUID": true,
+}
+
+// exportedName converts a field or table name such as "created_at" or
+// "userId" into an exported identifier such as "CreatedAt" or "UserID".
+func exportedName(s string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	runes := []rune(s)
+	for i, c := range runes {
+		switch {
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			flush()
+		case unicode.IsUpper(c) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			word = append(word, c)
+		default:
+			word = append(word, c)
+		}
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+	name := b.String()
+	if name == "" {
+		return "Field"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		name = "F" + name
+	}
+	return name
+}
+
+func isIdentifier(s string) bool {
+	for i, c := range s {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// uniqueName returns name, or name with a numeric suffix when it is taken,
+// and marks the result as taken.
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	taken[unique] = true
+	return unique
+}
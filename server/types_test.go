@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ─── generate_types ─────────────────────────────────────────────────────────
+
+func sampleTypeTree() *typeNode {
+	return buildTypeTree(inferSchema([]map[string]interface{}{
+		{
+			"id":         "1",
+			"score":      10.0,
+			"created_at": time.Now(),
+			"address":    map[string]interface{}{"city": "Paris", "zip": "75001"},
+			"items":      []interface{}{map[string]interface{}{"sku": "a", "qty": 1.0}},
+			"nickname":   nil,
+		},
+		{
+			"id":         "2",
+			"score":      "n/a",
+			"created_at": time.Now(),
+			"address":    map[string]interface{}{"city": "Lyon"},
+			"items":      []interface{}{},
+			"nickname":   "bo",
+		},
+	}))
+}
+
+func TestGenerateJSONSchema(t *testing.T) {
+	schema := generateJSONSchema("Order", sampleTypeTree())
+	if schema["$schema"] != jsonSchemaDialect || schema["title"] != "Order" {
+		t.Errorf("unexpected header: %v", schema)
+	}
+	if want := []string{"address", "created_at", "id", "items", "nickname", "score"}; !reflect.DeepEqual(schema["required"], want) {
+		t.Errorf("expected required %v, got %v", want, schema["required"])
+	}
+	props := schema["properties"].(map[string]interface{})
+	if score := props["score"].(map[string]interface{}); !reflect.DeepEqual(score["type"], []interface{}{"number", "string"}) {
+		t.Errorf("expected score to be number or string, got %v", score)
+	}
+	if created := props["created_at"].(map[string]interface{}); created["format"] != "date-time" {
+		t.Errorf("expected a date-time, got %v", created)
+	}
+	address := props["address"].(map[string]interface{})
+	if !reflect.DeepEqual(address["required"], []string{"city"}) {
+		t.Errorf("expected only city to be required, got %v", address["required"])
+	}
+	items := props["items"].(map[string]interface{})["items"].(map[string]interface{})
+	if items["type"] != "object" || items["properties"].(map[string]interface{})["sku"] == nil {
+		t.Errorf("expected item objects with a sku, got %v", items)
+	}
+}
+
+func TestGenerateGo(t *testing.T) {
+	src := generateGo("Order", sampleTypeTree(), "id")
+	if _, err := parser.ParseFile(token.NewFileSet(), "order.go", "package model\n\n"+src, 0); err != nil {
+		t.Fatalf("generated Go does not parse: %v\n%s", err, src)
+	}
+	for _, want := range []string{
+		"type Order struct",
+		"type OrderAddress struct",
+		"type OrderItemsItem struct",
+		"`json:\"id\" rethinkdb:\"id,omitempty\"`",
+		"Zip  string `json:\"zip,omitempty\" rethinkdb:\"zip,omitempty\"`",
+		"Nickname  *string",
+		"Score     interface{}",
+		"CreatedAt time.Time",
+		"Items     []OrderItemsItem",
+		"\"time\"",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("expected %q in:\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateTypeScript(t *testing.T) {
+	src := generateTypeScript("Order", sampleTypeTree())
+	for _, want := range []string{
+		"export interface Order {",
+		"export interface OrderAddress {",
+		"  zip?: string;",
+		"  score: number | string;",
+		"  nickname: null | string;",
+		"  created_at: Date;",
+		"  items: OrderItemsItem[];",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("expected %q in:\n%s", want, src)
+		}
+	}
+}
+
+func TestExportedName(t *testing.T) {
+	tests := map[string]string{
+		"created_at": "CreatedAt",
+		"userId":     "UserID",
+		"id":         "ID",
+		"2fa":        "F2fa",
+		"mcp-users":  "McpUsers",
+		"":           "Field",
+	}
+	for in, want := range tests {
+		if got := exportedName(in); got != want {
+			t.Errorf("exportedName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGenerateTypes_Table(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.GenerateTypes(context.Background(), &mcp.CallToolRequest{}, GenerateTypesInput{
+		Database: testDB,
+		Table:    testTable,
+		TypeName: "User",
+		Formats:  []string{"go", "typescript"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.JSONSchema != nil {
+		t.Error("expected no JSON Schema when not requested")
+	}
+	if !strings.Contains(output.Go, "type User struct") || !strings.Contains(output.Go, "Age    float64") {
+		t.Errorf("unexpected Go output:\n%s", output.Go)
+	}
+	if !strings.Contains(output.TypeScript, "  name: string;") {
+		t.Errorf("unexpected TypeScript output:\n%s", output.TypeScript)
+	}
+}
+
+func TestGenerateTypes_UnknownFormat(t *testing.T) {
+	srv := newTestServer()
+	_, _, err := srv.GenerateTypes(context.Background(), &mcp.CallToolRequest{}, GenerateTypesInput{
+		Database: testDB,
+		Table:    testTable,
+		Formats:  []string{"rust"},
+	})
+	if err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
@@ -0,0 +1,273 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// TableSchemas holds the JSON Schemas that documents written to a table must
+// satisfy, keyed by "database.table". Schemas use JSON Schema draft 2020-12.
+type TableSchemas struct {
+	schemas map[string]*jsonschema.Resolved
+}
+
+// LoadTableSchemas reads a JSON object mapping "database.table" to a JSON
+// Schema from the file at filename.
+func LoadTableSchemas(filename string) (*TableSchemas, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema file: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse schema file: %w", err)
+	}
+	schemas := &TableSchemas{schemas: make(map[string]*jsonschema.Resolved, len(raw))}
+	for name, schema := range raw {
+		if db, table, ok := strings.Cut(name, "."); !ok || db == "" || table == "" {
+			return nil, fmt.Errorf("schema file key %q must have the form database.table", name)
+		}
+		resolved, err := resolveSchema(schema)
+		if err != nil {
+			return nil, fmt.Errorf("schema for %s: %w", name, err)
+		}
+		schemas.schemas[name] = resolved
+	}
+	return schemas, nil
+}
+
+// resolveSchema parses and prepares a JSON Schema for validation.
+func resolveSchema(data []byte) (*jsonschema.Resolved, error) {
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	if schema.Schema != "" && schema.Schema != jsonSchemaDialect {
+		return nil, fmt.Errorf("unsupported $schema %q: only %s is supported", schema.Schema, jsonSchemaDialect)
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	return resolved, nil
+}
+
+// ValidationError reports why one document failed its table's schema.
+type ValidationError struct {
+	// Index is the position of the document in data; 0 for a single document.
+	Index int `json:"index"`
+	// Key is the document's primary key, when it has one.
+	Key   interface{} `json:"key,omitempty"`
+	Error string      `json:"error"`
+}
+
+// isSchemaTable reports whether db.table is the schema table.
+func (s *RethinkDBServer) isSchemaTable(db, table string) bool {
+	return s.schemaTable != "" && s.schemaTable == db+"."+table
+}
+
+// tableSchema returns the schema for db.table, or nil when none is
+// registered. Schemas in the schema file take precedence over the schema
+// table, which is read on every call so edits apply immediately.
+func (s *RethinkDBServer) tableSchema(ctx context.Context, db, table string) (*jsonschema.Resolved, error) {
+	name := db + "." + table
+	if s.schemas != nil {
+		if schema, ok := s.schemas.schemas[name]; ok {
+			return schema, nil
+		}
+	}
+	if s.schemaTable == "" {
+		return nil, nil
+	}
+
+	schemaDB, schemaTable, _ := strings.Cut(s.schemaTable, ".")
+	cursor, err := r.DB(schemaDB).Table(schemaTable).Get(name).Field("schema").Default(nil).Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema for %s from %s: %w", name, s.schemaTable, err)
+	}
+	defer cursor.Close()
+	var raw interface{}
+	if err := cursor.One(&raw); err != nil && err != r.ErrEmptyResult {
+		return nil, fmt.Errorf("failed to read schema for %s from %s: %w", name, s.schemaTable, err)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema for %s from %s: %w", name, s.schemaTable, err)
+	}
+	schema, err := resolveSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("schema for %s in %s: %w", name, s.schemaTable, err)
+	}
+	return schema, nil
+}
+
+// validateDocuments checks the documents about to be written to db.table
+// against the table's schema, if it has one. Updates are validated as the
+// document would look afterwards, the stored document with the update
+// merged in; updates to documents that do not exist write nothing and are
+// skipped.
+func (s *RethinkDBServer) validateDocuments(ctx context.Context, db, table, operation string, docs []interface{}) ([]ValidationError, error) {
+	schema, err := s.tableSchema(ctx, db, table)
+	if err != nil || schema == nil {
+		return nil, err
+	}
+	pk, err := s.primaryKey(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored map[interface{}]map[string]interface{}
+	if isUpdate(operation) {
+		if stored, err = s.storedDocuments(ctx, db, table, pk, docs); err != nil {
+			return nil, err
+		}
+	}
+
+	var failures []ValidationError
+	for i, doc := range docs {
+		var key interface{}
+		obj, isObject := doc.(map[string]interface{})
+		if isObject {
+			key = obj[pk]
+		}
+		instance := doc
+		existing, found := stored[hashableKey(key)]
+		if isUpdate(operation) && !found {
+			continue
+		}
+		if found && isObject {
+			// Round-trip through JSON so stored times and binary values
+			// validate as the strings clients see.
+			data, err := json.Marshal(mergeDocument(existing, obj))
+			if err != nil {
+				return nil, fmt.Errorf("failed to merge document %d: %w", i, err)
+			}
+			if err := json.Unmarshal(data, &instance); err != nil {
+				return nil, fmt.Errorf("failed to merge document %d: %w", i, err)
+			}
+		}
+		if err := schema.Validate(instance); err != nil {
+			failures = append(failures, ValidationError{Index: i, Key: key, Error: err.Error()})
+		}
+	}
+	return failures, nil
+}
+
+// validateUpdate checks the documents an update by keys or filter would
+// store, as computed by the query updated, against the table's schema. The
+// Index of a failure counts the matched documents.
+func (s *RethinkDBServer) validateUpdate(ctx context.Context, db, table string, updated r.Term) ([]ValidationError, error) {
+	schema, err := s.tableSchema(ctx, db, table)
+	if err != nil || schema == nil {
+		return nil, err
+	}
+	pk, err := s.primaryKey(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := updated.Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute updated documents: %w", err)
+	}
+	defer cursor.Close()
+	var docs []map[string]interface{}
+	if err := cursor.All(&docs); err != nil {
+		return nil, fmt.Errorf("failed to compute updated documents: %w", err)
+	}
+
+	var failures []ValidationError
+	for i, doc := range docs {
+		// Round-trip through JSON as validateDocuments does for merges.
+		var instance interface{}
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read updated document %d: %w", i, err)
+		}
+		if err := json.Unmarshal(data, &instance); err != nil {
+			return nil, fmt.Errorf("failed to read updated document %d: %w", i, err)
+		}
+		if err := schema.Validate(instance); err != nil {
+			failures = append(failures, ValidationError{Index: i, Key: doc[pk], Error: err.Error()})
+		}
+	}
+	return failures, nil
+}
+
+// storedDocuments fetches the documents whose primary keys appear in docs,
+// keyed by primary key.
+func (s *RethinkDBServer) storedDocuments(ctx context.Context, db, table, pk string, docs []interface{}) (map[interface{}]map[string]interface{}, error) {
+	var keys []interface{}
+	for _, doc := range docs {
+		if obj, ok := doc.(map[string]interface{}); ok {
+			if key, ok := obj[pk]; ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := r.DB(db).Table(table).GetAll(keys...).Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read documents to validate: %w", err)
+	}
+	defer cursor.Close()
+	var found []map[string]interface{}
+	if err := cursor.All(&found); err != nil {
+		return nil, fmt.Errorf("failed to read documents to validate: %w", err)
+	}
+	stored := make(map[interface{}]map[string]interface{}, len(found))
+	for _, doc := range found {
+		stored[hashableKey(doc[pk])] = doc
+	}
+	return stored, nil
+}
+
+// hashableKey returns a primary key usable as a map key. Compound keys are
+// arrays, which are not comparable, so they are keyed by their JSON form.
+func hashableKey(key interface{}) interface{} {
+	if _, ok := key.([]interface{}); ok {
+		data, _ := json.Marshal(key)
+		return string(data)
+	}
+	return key
+}
+
+// mergeDocument returns base with patch merged in the way RethinkDB's update
+// merges: nested objects are merged recursively and other values replaced.
+func mergeDocument(base, patch map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(patch))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range patch {
+		if sub, ok := v.(map[string]interface{}); ok {
+			if existing, ok := merged[k].(map[string]interface{}); ok {
+				merged[k] = mergeDocument(existing, sub)
+				continue
+			}
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// documentList returns data as a list of documents: the elements of an
+// array, or data itself.
+func documentList(data interface{}) []interface{} {
+	if list, ok := data.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{data}
+}
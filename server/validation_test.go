@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── write_data schema validation ───────────────────────────────────────────
+
+const testUserSchema = `{
+	"type": "object",
+	"required": ["id", "name", "age"],
+	"properties": {
+		"id": {"type": "string"},
+		"name": {"type": "string", "minLength": 1},
+		"age": {"type": "number", "minimum": 0}
+	}
+}`
+
+func writeSchemaFile(t *testing.T, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "schemas.json")
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func newValidatingServer(t *testing.T) *RethinkDBServer {
+	t.Helper()
+	schemas, err := LoadTableSchemas(writeSchemaFile(t, `{"`+testDB+`.`+testTable+`": `+testUserSchema+`}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return NewRethinkDBServer(testSession, WithTableSchemas(schemas))
+}
+
+func TestLoadTableSchemas_Invalid(t *testing.T) {
+	invalid := []string{
+		`{"users": {"type": "object"}}`,
+		`{"app.users": {"$schema": "http://json-schema.org/draft-07/schema#"}}`,
+		`{"app.users": {"type": 5}}`,
+		`[]`,
+	}
+	for _, content := range invalid {
+		if _, err := LoadTableSchemas(writeSchemaFile(t, content)); err == nil {
+			t.Errorf("%s: expected an error", content)
+		}
+	}
+}
+
+func TestMergeDocument(t *testing.T) {
+	merged := mergeDocument(
+		map[string]interface{}{"id": "1", "address": map[string]interface{}{"city": "Paris", "zip": "75001"}, "tags": []interface{}{"a"}},
+		map[string]interface{}{"address": map[string]interface{}{"city": "Lyon"}, "tags": []interface{}{"b"}},
+	)
+	want := map[string]interface{}{"id": "1", "address": map[string]interface{}{"city": "Lyon", "zip": "75001"}, "tags": []interface{}{"b"}}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("expected %v, got %v", want, merged)
+	}
+}
+
+func TestWriteData_SchemaRejectsInvalidDocuments(t *testing.T) {
+	srv := newValidatingServer(t)
+	defer r.DB(testDB).Table(testTable).GetAll("valid_1", "invalid_1").Delete().RunWrite(testSession)
+
+	res, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database: testDB,
+		Table:    testTable,
+		Data:     json.RawMessage(`[{"id": "valid_1", "name": "Ok", "age": 20}, {"id": "invalid_1", "name": "", "age": -1}]`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res == nil || !res.IsError {
+		t.Error("expected an error result")
+	}
+	if len(output.ValidationErrors) != 1 || output.ValidationErrors[0].Index != 1 || output.ValidationErrors[0].Key != "invalid_1" {
+		t.Errorf("expected document 1 to fail, got %+v", output.ValidationErrors)
+	}
+	if output.Inserted != 0 {
+		t.Errorf("expected nothing inserted, got %d", output.Inserted)
+	}
+
+	cursor, _ := r.DB(testDB).Table(testTable).Get("valid_1").Run(testSession)
+	defer cursor.Close()
+	if !cursor.IsNil() {
+		t.Error("expected the valid document not to be written either")
+	}
+}
+
+func TestWriteData_SchemaValidatesMergedUpdate(t *testing.T) {
+	srv := newValidatingServer(t)
+
+	// Alice is stored with name and age, so a partial update is complete
+	// once merged.
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Data:      json.RawMessage(`{"id": "1", "age": 30}`),
+		Operation: "update",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(output.ValidationErrors) != 0 {
+		t.Errorf("expected the merged document to validate, got %+v", output.ValidationErrors)
+	}
+
+	res, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Data:      json.RawMessage(`{"id": "1", "age": "thirty"}`),
+		Operation: "update",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res == nil || !res.IsError || len(output.ValidationErrors) != 1 {
+		t.Errorf("expected the update to be rejected, got %+v", output)
+	}
+}
+
+func TestWriteData_SchemaTable(t *testing.T) {
+	schemaTable := "mcp_test_schemas"
+	r.DB(testDB).TableCreate(schemaTable).RunWrite(testSession)
+	defer r.DB(testDB).TableDrop(schemaTable).RunWrite(testSession)
+
+	var schema map[string]interface{}
+	json.Unmarshal([]byte(testUserSchema), &schema)
+	r.DB(testDB).Table(schemaTable).Insert(map[string]interface{}{
+		"id":     testDB + "." + testTable,
+		"schema": schema,
+	}).RunWrite(testSession)
+
+	srv := NewRethinkDBServer(testSession, WithSchemaTable(testDB+"."+schemaTable))
+	res, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database: testDB,
+		Table:    testTable,
+		Data:     json.RawMessage(`{"id": "schema_table_1", "name": "NoAge"}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res == nil || !res.IsError || len(output.ValidationErrors) != 1 {
+		t.Errorf("expected the document to be rejected, got %+v", output)
+	}
+
+	// Tables without a schema are written unchecked.
+	_, output, err = srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database: testDB,
+		Table:    testJoinTable,
+		Data:     json.RawMessage(`{"id": "schema_table_2"}`),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer r.DB(testDB).Table(testJoinTable).Get("schema_table_2").Delete().RunWrite(testSession)
+	if output.Inserted != 1 {
+		t.Errorf("expected 1 inserted, got %d", output.Inserted)
+	}
+}
+
+func TestSchemaTableIsProtected(t *testing.T) {
+	schemaTable := "mcp_test_schemas"
+	srv := NewRethinkDBServer(testSession, WithSchemaTable(testDB+"."+schemaTable), WithAdmin(true), WithReQLPermissions(ReQLPermissions{Writes: true}))
+	ctx := context.Background()
+
+	_, _, err := srv.WriteData(ctx, &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     schemaTable,
+		Operation: "delete",
+		Data:      json.RawMessage(`{"id": "` + testDB + `.` + testTable + `"}`),
+	})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("write_data: expected ErrAccessDenied, got %v", err)
+	}
+	_, _, err = srv.TableDrop(ctx, &mcp.CallToolRequest{}, TableDropInput{Database: testDB, Table: schemaTable})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("table_drop: expected ErrAccessDenied, got %v", err)
+	}
+	_, _, err = srv.IndexDrop(ctx, &mcp.CallToolRequest{}, IndexDropInput{Database: testDB, Table: schemaTable, Index: "x"})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("index_drop: expected ErrAccessDenied, got %v", err)
+	}
+	_, _, err = srv.RunReQL(ctx, &mcp.CallToolRequest{}, RunReQLInput{
+		Query: `r.db('` + testDB + `').table('` + schemaTable + `').delete()`,
+	})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("run_reql: expected ErrAccessDenied, got %v", err)
+	}
+}
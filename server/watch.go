@@ -0,0 +1,455 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+const (
+	// watchURIPrefix is the scheme and path of changefeed resource URIs.
+	watchURIPrefix = "rethinkdb://watch/"
+	// defaultWatchBuffer is how many change events a watch keeps by default.
+	defaultWatchBuffer = 100
+	// maxWatchBuffer caps the buffer_size a client may request.
+	maxWatchBuffer = 1000
+)
+
+// ErrTooManyWatches is returned by watch_table when the session already has
+// as many open changefeeds as the server allows.
+var ErrTooManyWatches = errors.New("too many open watches")
+
+// ChangeEvent is a single change received from a changefeed.
+type ChangeEvent struct {
+	// Seq numbers the events of a watch from 1, so clients can tell which
+	// events they have already seen.
+	Seq int64 `json:"seq"`
+	// Type is the change type reported by RethinkDB: add, remove, change,
+	// initial, uninitial or state.
+	Type       string      `json:"type,omitempty"`
+	OldVal     interface{} `json:"old_val"`
+	NewVal     interface{} `json:"new_val"`
+	ReceivedAt time.Time   `json:"received_at"`
+}
+
+// watch is an open changefeed and the latest events it has received.
+type watch struct {
+	id       string
+	uri      string
+	database string
+	table    string
+	filter   map[string]interface{}
+	session  *mcp.ServerSession
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mu      sync.Mutex
+	events  []ChangeEvent
+	size    int
+	seq     int64
+	dropped int64
+	err     error
+	closed  bool
+}
+
+// add appends an event, dropping the oldest one when the buffer is full.
+func (w *watch) add(change map[string]interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.seq++
+	event := ChangeEvent{
+		Seq:        w.seq,
+		OldVal:     change["old_val"],
+		NewVal:     change["new_val"],
+		ReceivedAt: time.Now().UTC(),
+	}
+	if t, ok := change["type"].(string); ok {
+		event.Type = t
+	}
+	if len(w.events) == w.size {
+		copy(w.events, w.events[1:])
+		w.events = w.events[:len(w.events)-1]
+		w.dropped++
+	}
+	w.events = append(w.events, event)
+}
+
+// WatchSnapshot is the content of a watch resource.
+type WatchSnapshot struct {
+	WatchID  string                 `json:"watch_id"`
+	Database string                 `json:"database"`
+	Table    string                 `json:"table"`
+	Filter   map[string]interface{} `json:"filter,omitempty"`
+	// Status is "running" while the feed is open and "closed" afterwards.
+	Status string `json:"status"`
+	// Error explains why the feed stopped, if it stopped on its own.
+	Error string `json:"error,omitempty"`
+	// Dropped counts events that were pushed out of the buffer.
+	Dropped int64         `json:"dropped"`
+	Events  []ChangeEvent `json:"events"`
+}
+
+func (w *watch) snapshot() WatchSnapshot {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	snap := WatchSnapshot{
+		WatchID:  w.id,
+		Database: w.database,
+		Table:    w.table,
+		Filter:   w.filter,
+		Status:   "running",
+		Dropped:  w.dropped,
+		Events:   append([]ChangeEvent{}, w.events...),
+	}
+	if w.closed {
+		snap.Status = "closed"
+	}
+	if w.err != nil {
+		snap.Error = w.err.Error()
+	}
+	return snap
+}
+
+// ─── Input/Output structs ────────────────────────────────────────────────────
+
+type WatchTableInput struct {
+	Database       string                 `json:"database" jsonschema:"The database name"`
+	Table          string                 `json:"table" jsonschema:"The table name"`
+	Filter         map[string]interface{} `json:"filter,omitempty" jsonschema:"Optional filter object using the filter syntax of query_table; only matching changes are reported"`
+	IncludeInitial bool                   `json:"include_initial,omitempty" jsonschema:"Emit the current matching documents as initial events before live changes"`
+	BufferSize     int                    `json:"buffer_size,omitempty" jsonschema:"Number of latest change events to keep (default 100, max 1000)"`
+}
+
+type WatchTableOutput struct {
+	WatchID    string `json:"watch_id"`
+	URI        string `json:"uri"`
+	BufferSize int    `json:"buffer_size"`
+}
+
+type UnwatchTableInput struct {
+	WatchID string `json:"watch_id" jsonschema:"The watch_id returned by watch_table"`
+}
+
+type UnwatchTableOutput struct {
+	WatchID string `json:"watch_id"`
+	Closed  bool   `json:"closed"`
+}
+
+// ─── Handlers ────────────────────────────────────────────────────────────────
+
+// WatchTable opens a changefeed on a table and exposes it as a resource at
+// rethinkdb://watch/<id>. Reading the resource returns the latest buffered
+// change events; subscribers receive notifications/resources/updated for each
+// new event. The feed is closed by unwatch_table or when the session that
+// opened it ends.
+func (s *RethinkDBServer) WatchTable(ctx context.Context, req *mcp.CallToolRequest, input WatchTableInput) (*mcp.CallToolResult, WatchTableOutput, error) {
+	if input.Database == "" || input.Table == "" {
+		return nil, WatchTableOutput{}, fmt.Errorf("database and table names are required")
+	}
+
+	if err := s.checkTables(input.Database, input.Table); err != nil {
+		return nil, WatchTableOutput{}, err
+	}
+
+	size := input.BufferSize
+	if size <= 0 {
+		size = defaultWatchBuffer
+	}
+	if size > maxWatchBuffer {
+		size = maxWatchBuffer
+	}
+
+	pred, err := compileFilter(input.Filter)
+	if err != nil {
+		return nil, WatchTableOutput{}, fmt.Errorf("invalid filter: %w", err)
+	}
+
+	query := r.DB(input.Database).Table(input.Table)
+	if pred != nil {
+		query = query.Filter(pred)
+	}
+	query = query.Changes(r.ChangesOpts{
+		IncludeInitial: input.IncludeInitial,
+		IncludeTypes:   true,
+	})
+
+	id, err := randomID()
+	if err != nil {
+		return nil, WatchTableOutput{}, err
+	}
+
+	// The feed outlives this call, so it runs under its own context rather
+	// than the request's.
+	feedCtx, cancel := context.WithCancel(context.Background())
+	cursor, err := query.Run(s.session, r.RunOpts{Context: feedCtx})
+	if err != nil {
+		cancel()
+		return nil, WatchTableOutput{}, fmt.Errorf("failed to open changefeed: %w", err)
+	}
+
+	w := &watch{
+		id:       id,
+		uri:      watchURIPrefix + id,
+		database: input.Database,
+		table:    input.Table,
+		filter:   input.Filter,
+		size:     size,
+		session:  sessionOf(req),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	if err := s.addWatch(w); err != nil {
+		cursor.Close()
+		cancel()
+		return nil, WatchTableOutput{}, err
+	}
+	go s.runWatch(feedCtx, w, cursor)
+
+	return nil, WatchTableOutput{
+		WatchID:    w.id,
+		URI:        w.uri,
+		BufferSize: size,
+	}, nil
+}
+
+func (s *RethinkDBServer) UnwatchTable(ctx context.Context, req *mcp.CallToolRequest, input UnwatchTableInput) (*mcp.CallToolResult, UnwatchTableOutput, error) {
+	if input.WatchID == "" {
+		return nil, UnwatchTableOutput{}, fmt.Errorf("watch_id is required")
+	}
+
+	w, err := s.lookupWatch(input.WatchID, sessionOf(req))
+	if err != nil {
+		return nil, UnwatchTableOutput{}, err
+	}
+	s.closeWatch(w)
+
+	return nil, UnwatchTableOutput{WatchID: w.id, Closed: true}, nil
+}
+
+// ReadWatch is the resource handler for rethinkdb://watch/<id>.
+func (s *RethinkDBServer) ReadWatch(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	w, err := s.lookupWatch(strings.TrimPrefix(uri, watchURIPrefix), req.Session)
+	if err != nil {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	data, err := json.Marshal(w.snapshot())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode watch: %w", err)
+	}
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{
+		URI:      uri,
+		MIMEType: "application/json",
+		Text:     string(data),
+	}}}, nil
+}
+
+// Subscribe accepts resource subscriptions for watches owned by the
+// subscribing session. Pass it as mcp.ServerOptions.SubscribeHandler.
+func (s *RethinkDBServer) Subscribe(ctx context.Context, req *mcp.SubscribeRequest) error {
+	_, err := s.lookupWatch(strings.TrimPrefix(req.Params.URI, watchURIPrefix), req.Session)
+	return err
+}
+
+// Unsubscribe is the counterpart of Subscribe. Pass it as
+// mcp.ServerOptions.UnsubscribeHandler.
+func (s *RethinkDBServer) Unsubscribe(ctx context.Context, req *mcp.UnsubscribeRequest) error {
+	return nil
+}
+
+// Close closes every open changefeed. Call it before closing the RethinkDB
+// session.
+func (s *RethinkDBServer) Close() {
+	s.watchMu.Lock()
+	watches := make([]*watch, 0, len(s.watches))
+	for _, w := range s.watches {
+		watches = append(watches, w)
+	}
+	s.watchMu.Unlock()
+
+	for _, w := range watches {
+		s.closeWatch(w)
+	}
+}
+
+// ─── Watch bookkeeping ───────────────────────────────────────────────────────
+
+// runWatch copies changes from cursor into w until the feed ends or is
+// closed, notifying subscribers of each change. A feed that ends on its
+// own, because its table was dropped or the connection failed, is removed
+// so it no longer counts toward its session's watches.
+func (s *RethinkDBServer) runWatch(ctx context.Context, w *watch, cursor *r.Cursor) {
+	defer close(w.done)
+	defer cursor.Close()
+
+	var change map[string]interface{}
+	for cursor.Next(&change) {
+		w.add(change)
+		change = nil
+		if s.mcpServer != nil {
+			s.mcpServer.ResourceUpdated(ctx, &mcp.ResourceUpdatedNotificationParams{URI: w.uri})
+		}
+	}
+
+	w.mu.Lock()
+	if err := cursor.Err(); err != nil && ctx.Err() == nil {
+		w.err = err
+	} else if ctx.Err() == nil {
+		w.err = errors.New("changefeed ended")
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	if ctx.Err() == nil {
+		s.removeWatch(w)
+	}
+}
+
+// addWatch registers w and publishes its resource, unless w's session
+// already has the maximum number of watches open.
+func (s *RethinkDBServer) addWatch(w *watch) error {
+	s.watchMu.Lock()
+	if s.maxWatches > 0 {
+		open := 0
+		for _, other := range s.watches {
+			if other.session == w.session {
+				open++
+			}
+		}
+		if open >= s.maxWatches {
+			s.watchMu.Unlock()
+			return fmt.Errorf("%w: this session already has %d; close one with unwatch_table first", ErrTooManyWatches, open)
+		}
+	}
+	if s.watches == nil {
+		s.watches = make(map[string]*watch)
+	}
+	s.watches[w.id] = w
+	watchSession := w.session != nil && !s.watchedSessions[w.session]
+	if watchSession {
+		if s.watchedSessions == nil {
+			s.watchedSessions = make(map[*mcp.ServerSession]bool)
+		}
+		s.watchedSessions[w.session] = true
+	}
+	s.watchMu.Unlock()
+
+	if s.mcpServer != nil {
+		s.mcpServer.AddResource(&mcp.Resource{
+			URI:         w.uri,
+			Name:        "watch-" + w.id,
+			Title:       fmt.Sprintf("Changes on %s.%s", w.database, w.table),
+			Description: "Latest change events from a changefeed opened with watch_table",
+			MIMEType:    "application/json",
+		}, s.ReadWatch)
+	}
+
+	if watchSession {
+		go s.closeSessionWatches(w.session)
+	}
+	return nil
+}
+
+// hideOtherWatches is receiving middleware that removes the watch resources
+// of other sessions from resources/list results. Resources are registered
+// server-wide, but a watch is private to the session that opened it.
+func (s *RethinkDBServer) hideOtherWatches(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		result, err := next(ctx, method, req)
+		list, ok := result.(*mcp.ListResourcesResult)
+		if err != nil || !ok {
+			return result, err
+		}
+		session, _ := req.GetSession().(*mcp.ServerSession)
+		visible := make([]*mcp.Resource, 0, len(list.Resources))
+		for _, res := range list.Resources {
+			id, isWatch := strings.CutPrefix(res.URI, watchURIPrefix)
+			if isWatch {
+				if _, err := s.lookupWatch(id, session); err != nil {
+					continue
+				}
+			}
+			visible = append(visible, res)
+		}
+		list.Resources = visible
+		return list, nil
+	}
+}
+
+// closeSessionWatches waits for session to end and closes its watches.
+func (s *RethinkDBServer) closeSessionWatches(session *mcp.ServerSession) {
+	session.Wait()
+
+	s.watchMu.Lock()
+	delete(s.watchedSessions, session)
+	var watches []*watch
+	for _, w := range s.watches {
+		if w.session == session {
+			watches = append(watches, w)
+		}
+	}
+	s.watchMu.Unlock()
+
+	for _, w := range watches {
+		s.closeWatch(w)
+	}
+}
+
+// lookupWatch returns the watch with the given id. Watches are private to
+// the session that opened them.
+func (s *RethinkDBServer) lookupWatch(id string, session *mcp.ServerSession) (*watch, error) {
+	s.watchMu.Lock()
+	w, ok := s.watches[id]
+	s.watchMu.Unlock()
+	if !ok || (w.session != nil && w.session != session) {
+		return nil, fmt.Errorf("unknown watch %q", id)
+	}
+	return w, nil
+}
+
+// closeWatch stops w's changefeed and removes it.
+func (s *RethinkDBServer) closeWatch(w *watch) {
+	w.cancel()
+	<-w.done
+	s.removeWatch(w)
+}
+
+// removeWatch unregisters w and removes its resource.
+func (s *RethinkDBServer) removeWatch(w *watch) {
+	s.watchMu.Lock()
+	delete(s.watches, w.id)
+	s.watchMu.Unlock()
+
+	if s.mcpServer != nil {
+		s.mcpServer.RemoveResources(w.uri)
+	}
+}
+
+// randomID returns a random 16 character hex identifier.
+func randomID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func sessionOf(req *mcp.CallToolRequest) *mcp.ServerSession {
+	if req == nil {
+		return nil
+	}
+	return req.Session
+}
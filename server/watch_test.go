@@ -0,0 +1,233 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── watch_table ────────────────────────────────────────────────────────────
+
+// waitForEvents polls w until it has buffered at least n events.
+func waitForEvents(t *testing.T, w *watch, n int) WatchSnapshot {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		snap := w.snapshot()
+		if len(snap.Events) >= n {
+			return snap
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d events, have %d", n, len(snap.Events))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestWatch_BufferDropsOldest(t *testing.T) {
+	w := &watch{size: 2}
+	for i := 0; i < 3; i++ {
+		w.add(map[string]interface{}{"new_val": map[string]interface{}{"n": i}, "type": "add"})
+	}
+	snap := w.snapshot()
+	if len(snap.Events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(snap.Events))
+	}
+	if snap.Events[0].Seq != 2 || snap.Events[1].Seq != 3 {
+		t.Errorf("expected events 2 and 3, got %d and %d", snap.Events[0].Seq, snap.Events[1].Seq)
+	}
+	if snap.Dropped != 1 {
+		t.Errorf("expected 1 dropped event, got %d", snap.Dropped)
+	}
+}
+
+func TestWatchTable_ReceivesChanges(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	_, output, err := srv.WatchTable(context.Background(), &mcp.CallToolRequest{}, WatchTableInput{
+		Database: testDB,
+		Table:    testTable,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.URI != watchURIPrefix+output.WatchID {
+		t.Errorf("unexpected uri %q", output.URI)
+	}
+
+	r.DB(testDB).Table(testTable).Insert(map[string]interface{}{
+		"id": "watch_test", "name": "Watcher", "age": 40, "status": "active",
+	}).RunWrite(testSession)
+	defer r.DB(testDB).Table(testTable).Get("watch_test").Delete().RunWrite(testSession)
+
+	w, err := srv.lookupWatch(output.WatchID, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	snap := waitForEvents(t, w, 1)
+	event := snap.Events[0]
+	if event.Type != "add" || event.OldVal != nil {
+		t.Errorf("expected an add event, got %+v", event)
+	}
+	if doc, ok := event.NewVal.(map[string]interface{}); !ok || doc["id"] != "watch_test" {
+		t.Errorf("expected new_val for watch_test, got %v", event.NewVal)
+	}
+
+	result, err := srv.ReadWatch(context.Background(), &mcp.ReadResourceRequest{
+		Params: &mcp.ReadResourceParams{URI: output.URI},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error reading resource: %v", err)
+	}
+	var read WatchSnapshot
+	if err := json.Unmarshal([]byte(result.Contents[0].Text), &read); err != nil {
+		t.Fatalf("invalid resource content: %v", err)
+	}
+	if read.Status != "running" || len(read.Events) == 0 {
+		t.Errorf("expected a running watch with events, got %+v", read)
+	}
+}
+
+func TestWatchTable_FilterAndIncludeInitial(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	_, output, err := srv.WatchTable(context.Background(), &mcp.CallToolRequest{}, WatchTableInput{
+		Database:       testDB,
+		Table:          testTable,
+		Filter:         map[string]interface{}{"status": "active"},
+		IncludeInitial: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w, err := srv.lookupWatch(output.WatchID, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	snap := waitForEvents(t, w, 2)
+	for _, event := range snap.Events {
+		if event.Type != "initial" {
+			continue
+		}
+		if doc, ok := event.NewVal.(map[string]interface{}); !ok || doc["status"] != "active" {
+			t.Errorf("expected only active documents, got %v", event.NewVal)
+		}
+	}
+}
+
+func TestUnwatchTable_ClosesFeed(t *testing.T) {
+	srv := newTestServer()
+	_, output, err := srv.WatchTable(context.Background(), &mcp.CallToolRequest{}, WatchTableInput{
+		Database: testDB,
+		Table:    testTable,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w, _ := srv.lookupWatch(output.WatchID, nil)
+
+	_, unwatch, err := srv.UnwatchTable(context.Background(), &mcp.CallToolRequest{}, UnwatchTableInput{
+		WatchID: output.WatchID,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !unwatch.Closed {
+		t.Error("expected closed to be true")
+	}
+	if snap := w.snapshot(); snap.Status != "closed" || snap.Error != "" {
+		t.Errorf("expected a cleanly closed watch, got status %q error %q", snap.Status, snap.Error)
+	}
+
+	_, err = srv.ReadWatch(context.Background(), &mcp.ReadResourceRequest{
+		Params: &mcp.ReadResourceParams{URI: output.URI},
+	})
+	if err == nil {
+		t.Error("expected reading a closed watch to fail")
+	}
+}
+
+func TestWatchTable_RemovedWhenFeedEnds(t *testing.T) {
+	r.DB(testDB).TableCreate("mcp_test_watch_drop").RunWrite(testSession)
+	defer r.DB(testDB).TableDrop("mcp_test_watch_drop").RunWrite(testSession)
+
+	srv := NewRethinkDBServer(testSession, WithMaxWatches(1))
+	_, output, err := srv.WatchTable(context.Background(), &mcp.CallToolRequest{}, WatchTableInput{
+		Database: testDB,
+		Table:    "mcp_test_watch_drop",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w, _ := srv.lookupWatch(output.WatchID, nil)
+
+	r.DB(testDB).TableDrop("mcp_test_watch_drop").RunWrite(testSession)
+	select {
+	case <-w.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the feed to end")
+	}
+	if snap := w.snapshot(); snap.Status != "closed" || snap.Error == "" {
+		t.Errorf("expected the feed to report why it ended, got status %q error %q", snap.Status, snap.Error)
+	}
+
+	if _, err := srv.lookupWatch(output.WatchID, nil); err == nil {
+		t.Error("expected the ended watch to be removed")
+	}
+	if err := srv.addWatch(&watch{id: "next"}); err != nil {
+		t.Errorf("expected the ended watch to stop counting toward the limit, got %v", err)
+	}
+}
+
+func TestWatchTable_DeniedByPolicy(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithPolicy(&Policy{DenyTables: []string{testDB + "." + testTable}}))
+	_, _, err := srv.WatchTable(context.Background(), &mcp.CallToolRequest{}, WatchTableInput{
+		Database: testDB,
+		Table:    testTable,
+	})
+	if err == nil {
+		t.Fatal("expected access to be denied")
+	}
+}
+
+func TestAddWatch_LimitsWatchesPerSession(t *testing.T) {
+	srv := NewRethinkDBServer(testSession, WithMaxWatches(1))
+	if err := srv.addWatch(&watch{id: "first"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := srv.addWatch(&watch{id: "second"}); !errors.Is(err, ErrTooManyWatches) {
+		t.Errorf("expected ErrTooManyWatches, got %v", err)
+	}
+}
+
+func TestHideOtherWatches(t *testing.T) {
+	mine, theirs := &mcp.ServerSession{}, &mcp.ServerSession{}
+	srv := newTestServer()
+	srv.watches = map[string]*watch{
+		"mine":   {id: "mine", session: mine},
+		"theirs": {id: "theirs", session: theirs},
+	}
+	next := func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		return &mcp.ListResourcesResult{Resources: []*mcp.Resource{
+			{URI: watchURIPrefix + "mine"},
+			{URI: watchURIPrefix + "theirs"},
+			{URI: "rethinkdb://other"},
+		}}, nil
+	}
+	result, err := srv.hideOtherWatches(next)(context.Background(), "resources/list", &mcp.ListResourcesRequest{Session: mine})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resources := result.(*mcp.ListResourcesResult).Resources
+	if len(resources) != 2 || resources[0].URI != watchURIPrefix+"mine" || resources[1].URI != "rethinkdb://other" {
+		t.Errorf("expected only this session's watch and other resources, got %v", resources)
+	}
+}
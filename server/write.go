@@ -0,0 +1,235 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// isUpdate reports whether operation merges fields into stored documents
+// found by primary key.
+func isUpdate(operation string) bool {
+	return operation == "update" || operation == "compare_and_set"
+}
+
+// documentKeys returns the primary key of every document, which update
+// needs to find the document it changes.
+func documentKeys(pk string, docs []interface{}) ([]interface{}, error) {
+	keys := make([]interface{}, 0, len(docs))
+	for i, doc := range docs {
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("document %d is not an object", i)
+		}
+		key, ok := obj[pk]
+		if !ok || key == nil {
+			return nil, fmt.Errorf("document %d has no primary key %q", i, pk)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// updateQuery updates each document with the given keys, merging in the
+// fields of docs. Documents that do not exist are skipped rather than
+// inserted.
+func updateQuery(t r.Term, pk string, docs, keys []interface{}, opts r.UpdateOpts) r.Term {
+	if len(docs) == 1 {
+		return t.Get(keys[0]).Update(docs[0], opts)
+	}
+	return r.Expr(docs).ForEach(func(doc r.Term) interface{} {
+		return t.Get(doc.Field(pk)).Update(doc, opts)
+	})
+}
+
+// deleteSelection returns the documents a delete removes. data is either an
+// object holding only the primary key, a filter object in query_table's
+// syntax, or an array of primary keys or documents carrying their primary
+// key. For deletes by key the keys are returned too.
+func deleteSelection(t r.Term, pk string, data interface{}) (r.Term, []interface{}, error) {
+	switch data := data.(type) {
+	case map[string]interface{}:
+		if key, ok := data[pk]; ok && len(data) == 1 {
+			return t.GetAll(key), []interface{}{key}, nil
+		}
+		filter, err := compileFilter(data)
+		if err != nil {
+			return r.Term{}, nil, fmt.Errorf("invalid filter: %w", err)
+		}
+		if filter == nil {
+			return r.Term{}, nil, fmt.Errorf("the delete filter is empty and would delete every document; pass primary keys or a filter that selects documents")
+		}
+		return t.Filter(filter), nil, nil
+
+	case []interface{}:
+		if len(data) == 0 {
+			return r.Term{}, nil, fmt.Errorf("no primary keys to delete")
+		}
+		keys := make([]interface{}, 0, len(data))
+		for i, item := range data {
+			if obj, ok := item.(map[string]interface{}); ok {
+				key, ok := obj[pk]
+				if !ok {
+					return r.Term{}, nil, fmt.Errorf("document %d has no primary key %q", i, pk)
+				}
+				item = key
+			}
+			keys = append(keys, item)
+		}
+		return t.GetAll(keys...), keys, nil
+	}
+	return r.Term{}, nil, fmt.Errorf("data for delete must be an object or an array of primary keys")
+}
+
+// missingKeys returns the keys for which db.table holds no document.
+func (s *RethinkDBServer) missingKeys(ctx context.Context, db, table string, keys []interface{}) ([]interface{}, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	t := r.DB(db).Table(table)
+	cursor, err := r.Expr(keys).Filter(func(key r.Term) interface{} {
+		return t.Get(key).Eq(nil)
+	}).Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up primary keys: %w", err)
+	}
+	defer cursor.Close()
+	var missing []interface{}
+	if err := cursor.All(&missing); err != nil {
+		return nil, fmt.Errorf("failed to look up primary keys: %w", err)
+	}
+	return missing, nil
+}
+
+// updatesWhere reports whether an update selects its documents with keys or
+// a filter rather than by the primary keys in data. Update operators always
+// need keys or a filter.
+func updatesWhere(input WriteDataInput, data interface{}) bool {
+	if input.Keys != nil || input.Filter != nil {
+		return true
+	}
+	doc, isObject := data.(map[string]interface{})
+	return isObject && hasUpdateOperators(doc)
+}
+
+// updateWhere applies an update document, plain or with update operators,
+// to the documents with the given keys or matching a filter. The update is
+// compiled into a single function, so each document is updated atomically.
+func (s *RethinkDBServer) updateWhere(ctx context.Context, what string, input WriteDataInput, data interface{}) (*mcp.CallToolResult, WriteDataOutput, error) {
+	doc, isObject := data.(map[string]interface{})
+	if !isObject {
+		return nil, WriteDataOutput{}, fmt.Errorf("an update by keys or filter takes a single update document")
+	}
+	update, err := compileUpdate(doc)
+	if err != nil {
+		return nil, WriteDataOutput{}, fmt.Errorf("invalid update: %w", err)
+	}
+
+	table := r.DB(input.Database).Table(input.Table)
+	var selection r.Term
+	switch {
+	case input.Keys != nil && input.Filter != nil:
+		return nil, WriteDataOutput{}, fmt.Errorf("pass either keys or filter, not both")
+	case input.Keys != nil:
+		if len(input.Keys) == 0 {
+			return nil, WriteDataOutput{}, fmt.Errorf("keys is empty: pass the primary keys of the documents to update")
+		}
+		selection = table.GetAll(input.Keys...)
+	case input.Filter != nil:
+		filter, err := compileFilter(input.Filter)
+		if err != nil {
+			return nil, WriteDataOutput{}, fmt.Errorf("invalid filter: %w", err)
+		}
+		if filter == nil {
+			return nil, WriteDataOutput{}, fmt.Errorf("the update filter is empty and would update every document; pass keys or a filter that selects documents")
+		}
+		selection = table.Filter(filter)
+	default:
+		return nil, WriteDataOutput{}, fmt.Errorf("update operators require keys or a filter to select documents")
+	}
+
+	output := WriteDataOutput{
+		Database:  input.Database,
+		Table:     input.Table,
+		Operation: "update",
+		DryRun:    input.DryRun,
+	}
+	if output.NotFound, err = s.missingKeys(ctx, input.Database, input.Table, input.Keys); err != nil {
+		return nil, WriteDataOutput{}, queryError(ctx, what, err)
+	}
+
+	if !input.DryRun {
+		matched, refusal, err := s.checkAffected(ctx, selection, "update", input.ConfirmCount)
+		if err != nil {
+			return nil, WriteDataOutput{}, queryError(ctx, what, err)
+		}
+		if refusal != "" {
+			output.FirstError, output.Matched = refusal, matched
+			return &mcp.CallToolResult{IsError: true}, output, nil
+		}
+	}
+
+	// The updated documents are computed with Merge, which applies an
+	// update document, literals included, the way Update does.
+	updated := func(row r.Term) interface{} {
+		return row.Merge(update(row))
+	}
+
+	failures, err := s.validateUpdate(ctx, input.Database, input.Table, selection.Map(updated))
+	if err != nil {
+		return nil, WriteDataOutput{}, queryError(ctx, what, err)
+	}
+	if len(failures) > 0 {
+		output.Errors = len(failures)
+		output.FirstError = fmt.Sprintf("%d updated documents would fail schema validation; nothing was written", len(failures))
+		output.ValidationErrors = failures
+		return &mcp.CallToolResult{IsError: true}, output, nil
+	}
+
+	if input.DryRun {
+		if err := s.previewUpdate(ctx, selection, updated, &output); err != nil {
+			return nil, WriteDataOutput{}, queryError(ctx, what, err)
+		}
+		return nil, output, nil
+	}
+
+	result, err := s.runWrite(ctx, selection.Update(update, r.UpdateOpts{ReturnChanges: input.wantsChanges()}))
+	if err != nil {
+		return nil, WriteDataOutput{}, queryError(ctx, what, fmt.Errorf("failed to execute update: %w", err))
+	}
+	addWriteResult(&output, result, returnedChanges(input))
+	if output.Errors > 0 {
+		return &mcp.CallToolResult{IsError: true}, output, nil
+	}
+	return nil, output, nil
+}
+
+// checkAffected counts the documents selection matches and refuses the
+// write when there are more than the server's maximum, unless confirm is
+// exactly the count. It returns the count and, when the write is refused,
+// the reason. Documents written between the count and the write are not
+// accounted for.
+func (s *RethinkDBServer) checkAffected(ctx context.Context, selection r.Term, operation string, confirm int) (int, string, error) {
+	if s.maxAffected <= 0 {
+		return 0, "", nil
+	}
+	cursor, err := selection.Count().Run(s.session, r.RunOpts{Context: ctx})
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to count affected documents: %w", err)
+	}
+	defer cursor.Close()
+	var count int
+	if err := cursor.One(&count); err != nil {
+		return 0, "", fmt.Errorf("failed to count affected documents: %w", err)
+	}
+
+	switch {
+	case count <= s.maxAffected || confirm == count:
+		return count, "", nil
+	case confirm != 0:
+		return count, fmt.Sprintf("confirm_count %d does not match the %d documents the %s matches; nothing was written", confirm, count, operation), nil
+	}
+	return count, fmt.Sprintf("%s matches %d documents, more than the limit of %d; nothing was written. Call again with confirm_count set to %d to proceed", operation, count, s.maxAffected, count), nil
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+)
+
+// ─── write_data by primary key ──────────────────────────────────────────────
+
+func TestDocumentKeys(t *testing.T) {
+	keys, err := documentKeys("sku", []interface{}{
+		map[string]interface{}{"sku": "a", "qty": 1.0},
+		map[string]interface{}{"sku": []interface{}{"b", 2.0}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []interface{}{"a", []interface{}{"b", 2.0}}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("expected %v, got %v", want, keys)
+	}
+
+	for _, docs := range [][]interface{}{
+		{map[string]interface{}{"id": "a"}},
+		{map[string]interface{}{"sku": nil}},
+		{"a"},
+	} {
+		if _, err := documentKeys("sku", docs); err == nil {
+			t.Errorf("%v: expected an error", docs)
+		}
+	}
+}
+
+func TestDeleteSelection(t *testing.T) {
+	table := r.DB("app").Table("items")
+	tests := []struct {
+		data interface{}
+		keys []interface{}
+	}{
+		{map[string]interface{}{"sku": "a"}, []interface{}{"a"}},
+		{[]interface{}{"a", map[string]interface{}{"sku": "b"}}, []interface{}{"a", "b"}},
+		{map[string]interface{}{"id": "a"}, nil},
+		{map[string]interface{}{"sku": "a", "qty": 1.0}, nil},
+	}
+	for _, tt := range tests {
+		_, keys, err := deleteSelection(table, "sku", tt.data)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tt.data, err)
+			continue
+		}
+		if !reflect.DeepEqual(keys, tt.keys) {
+			t.Errorf("%v: expected keys %v, got %v", tt.data, tt.keys, keys)
+		}
+	}
+
+	for _, data := range []interface{}{[]interface{}{}, []interface{}{map[string]interface{}{"id": "a"}}, "a", map[string]interface{}{}} {
+		if _, _, err := deleteSelection(table, "sku", data); err == nil {
+			t.Errorf("%v: expected an error", data)
+		}
+	}
+}
+
+func TestWriteData_UpdateReportsNotFound(t *testing.T) {
+	srv := newTestServer()
+	defer r.DB(testDB).Table(testTable).Get("2").Update(map[string]interface{}{"age": 25}).RunWrite(testSession)
+
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Data:      json.RawMessage(`[{"id": "2", "age": 27}, {"id": "missing_1", "age": 1}]`),
+		Operation: "update",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Replaced != 1 || output.Inserted != 0 {
+		t.Errorf("expected 1 replaced and nothing inserted, got %+v", output)
+	}
+	if !reflect.DeepEqual(output.NotFound, []interface{}{"missing_1"}) {
+		t.Errorf("expected missing_1 to be reported, got %v", output.NotFound)
+	}
+
+	cursor, _ := r.DB(testDB).Table(testTable).Get("missing_1").Run(testSession)
+	defer cursor.Close()
+	if !cursor.IsNil() {
+		t.Error("expected update not to insert missing documents")
+	}
+}
+
+func TestWriteData_UpdateRequiresPrimaryKey(t *testing.T) {
+	srv := newTestServer()
+	_, _, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Data:      json.RawMessage(`{"name": "NoKey"}`),
+		Operation: "update",
+	})
+	if err == nil {
+		t.Error("expected an update without a primary key to be rejected")
+	}
+}
+
+func TestWriteData_DeleteByKeys(t *testing.T) {
+	srv := newTestServer()
+	r.DB(testDB).Table(testTable).Insert([]map[string]interface{}{
+		{"id": "keys_1"}, {"id": "keys_2"},
+	}).RunWrite(testSession)
+
+	_, output, err := srv.WriteData(context.Background(), &mcp.CallToolRequest{}, WriteDataInput{
+		Database:  testDB,
+		Table:     testTable,
+		Data:      json.RawMessage(`["keys_1", "keys_2", "keys_missing"]`),
+		Operation: "delete",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Deleted != 2 {
+		t.Errorf("expected 2 deleted, got %d", output.Deleted)
+	}
+	if !reflect.DeepEqual(output.NotFound, []interface{}{"keys_missing"}) {
+		t.Errorf("expected keys_missing to be reported, got %v", output.NotFound)
+	}
+}